@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+)
+
+const usage = `usage: kbg-admin <command> [flags]
+
+commands:
+  keys issue  -project <id|*> -scopes ingest,activate,delete,search[,impersonate][,admin] [-desc text]
+  keys revoke -id <key id>
+  keys list
+
+The admin scope covers export/import and ACL updates on the key's project;
+migrations, collections and snapshots also need -project '*'.
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 3 || os.Args[1] != "keys" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config load: %v", err)
+	}
+
+	cmd, args := os.Args[2], os.Args[3:]
+	fs := flag.NewFlagSet("keys "+cmd, flag.ExitOnError)
+	file := fs.String("file", cfg.Auth.APIKeysFile, "API key store (default $KBG_AUTH_API_KEYS_FILE)")
+	project := fs.String("project", "", "project the key is valid for, or * for all projects")
+	scopes := fs.String("scopes", "", "comma-separated scopes")
+	desc := fs.String("desc", "", "free-form description")
+	id := fs.String("id", "", "key id")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		log.Fatalf("no key store: pass -file or set KBG_AUTH_API_KEYS_FILE")
+	}
+	store, err := apikey.Open(*file)
+	if err != nil {
+		log.Fatalf("open %s: %v", *file, err)
+	}
+
+	switch cmd {
+	case "issue":
+		if *project == "" {
+			log.Fatalf("-project is required")
+		}
+		sc, err := apikey.ParseScopes(*scopes)
+		if err != nil {
+			log.Fatalf("-scopes: %v", err)
+		}
+		k, plaintext, err := store.Issue(*project, sc, *desc)
+		if err != nil {
+			log.Fatalf("issue: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "issued key %s for project %s (scopes %s); it is shown only once:\n", k.ID, k.ProjectID, joinScopes(k.Scopes))
+		fmt.Println(plaintext)
+	case "revoke":
+		if *id == "" {
+			log.Fatalf("-id is required")
+		}
+		if err := store.Revoke(*id); err != nil {
+			log.Fatalf("revoke: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "revoked key %s\n", *id)
+	case "list":
+		keys := store.List()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPROJECT\tSCOPES\tCREATED\tREVOKED\tDESCRIPTION")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.ProjectID, joinScopes(k.Scopes), k.CreatedAt.Format(time.RFC3339), revoked, k.Description)
+		}
+		_ = tw.Flush()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func joinScopes(scopes []apikey.Scope) string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return strings.Join(out, ",")
+}
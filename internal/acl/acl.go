@@ -6,9 +6,14 @@ type DocACL struct {
 	Public         bool     `json:"acl_public"`
 	ExternalPublic bool     `json:"acl_external_public"`
 	Allow          []string `json:"acl_allow"`
+	// Deny overrides every grant above, including public flags.
+	Deny []string `json:"acl_deny"`
 }
 
 func Allowed(pr types.Principal, a DocACL) bool {
+	if intersects(a.Deny, pr.Groups) {
+		return false
+	}
 	// Customer visibility never uses internal-public.
 	switch pr.Type {
 	case types.PrincipalCustomerUser:
@@ -33,3 +33,39 @@ func TestAllowed_GroupIntersection(t *testing.T) {
 		t.Fatalf("expected allowed")
 	}
 }
+
+func TestAllowed_DenyOverridesAllow(t *testing.T) {
+	cases := []struct {
+		name string
+		pr   types.Principal
+		acl  DocACL
+		want bool
+	}{
+		{"internal public except contractors",
+			types.Principal{Type: types.PrincipalInternalUser, Groups: []string{"staff", "contractors"}},
+			DocACL{Public: true, Deny: []string{"contractors"}}, false},
+		{"internal public, not denied",
+			types.Principal{Type: types.PrincipalInternalUser, Groups: []string{"staff"}},
+			DocACL{Public: true, Deny: []string{"contractors"}}, true},
+		{"all customers except customer:x",
+			types.Principal{Type: types.PrincipalCustomerUser, Groups: []string{"customer:x"}},
+			DocACL{ExternalPublic: true, Deny: []string{"customer:x"}}, false},
+		{"other customer still allowed",
+			types.Principal{Type: types.PrincipalCustomerUser, Groups: []string{"customer:acme"}},
+			DocACL{ExternalPublic: true, Deny: []string{"customer:x"}}, true},
+		{"deny beats explicit allow",
+			types.Principal{Type: types.PrincipalService, Groups: []string{"indexer", "quarantined"}},
+			DocACL{Allow: []string{"indexer"}, Deny: []string{"quarantined"}}, false},
+		{"service allowed by group",
+			types.Principal{Type: types.PrincipalService, Groups: []string{"indexer"}},
+			DocACL{Allow: []string{"indexer"}, Deny: []string{"quarantined"}}, true},
+		{"unknown type never allowed",
+			types.Principal{Type: "robot", Groups: []string{"indexer"}},
+			DocACL{Public: true, ExternalPublic: true, Allow: []string{"indexer"}}, false},
+	}
+	for _, tc := range cases {
+		if got := Allowed(tc.pr, tc.acl); got != tc.want {
+			t.Fatalf("%s: Allowed = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/acl"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+var (
+	propGroups   = []string{"eng", "ops", "contractors", "customer:acme", "customer:beta"}
+	propProjects = []string{"p1", "p2", "p3"}
+	propTypes    = []types.PrincipalType{types.PrincipalInternalUser, types.PrincipalCustomerUser, types.PrincipalService, "", "admin"}
+)
+
+// aclCase is a random principal, search scope and chunk payload.
+type aclCase struct {
+	Principal types.Principal
+	Scope     []string
+	Payload   map[string]any
+}
+
+func randomSubset(r *rand.Rand, from []string) []string {
+	var out []string
+	for _, x := range from {
+		if r.Intn(3) == 0 {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func (aclCase) Generate(r *rand.Rand, _ int) reflect.Value {
+	c := aclCase{
+		Principal: types.Principal{
+			Type:   propTypes[r.Intn(len(propTypes))],
+			ID:     "u",
+			Groups: randomSubset(r, propGroups),
+		},
+		Scope: randomSubset(r, propProjects),
+	}
+	if len(c.Scope) == 0 {
+		c.Scope = []string{propProjects[r.Intn(len(propProjects))]}
+	}
+	allow := []any{}
+	for _, g := range randomSubset(r, propGroups) {
+		allow = append(allow, g)
+	}
+	c.Payload = map[string]any{
+		"project_id":          propProjects[r.Intn(len(propProjects))],
+		"is_active":           r.Intn(2) == 0,
+		"deleted":             r.Intn(4) == 0,
+		"acl_public":          r.Intn(2) == 0,
+		"acl_external_public": r.Intn(3) == 0,
+		"acl_allow":           allow,
+	}
+	// Older points have no acl_deny at all.
+	if r.Intn(2) == 0 {
+		deny := []any{}
+		for _, g := range randomSubset(r, propGroups) {
+			deny = append(deny, g)
+		}
+		c.Payload["acl_deny"] = deny
+	}
+	return reflect.ValueOf(c)
+}
+
+func TestProperty_ACLFilterAgreesWithAllowed(t *testing.T) {
+	prop := func(c aclCase) bool {
+		want := acl.Allowed(c.Principal, docACLFromPayload(c.Payload))
+		got := evalFilter(buildACLFilter(c.Principal), "id", c.Payload)
+		if got != want {
+			t.Logf("principal=%+v payload=%v filter=%v: filter=%v allowed=%v", c.Principal, c.Payload, buildACLFilter(c.Principal), got, want)
+		}
+		return got == want
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 5000, Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProperty_SearchFilterAgreesWithRecheck(t *testing.T) {
+	prop := func(c aclCase) bool {
+		f := andFilters(buildBaseFilter(c.Scope), buildACLFilter(c.Principal))
+		got := evalFilter(f, "id", c.Payload)
+		want := hitViolation(c.Principal, c.Scope, c.Payload) == ""
+		if got != want {
+			t.Logf("principal=%+v scope=%v payload=%v: filter=%v recheck=%v", c.Principal, c.Scope, c.Payload, got, want)
+		}
+		return got == want
+	}
+	if err := quick.Check(prop, &quick.Config{MaxCount: 5000, Rand: rand.New(rand.NewSource(2))}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHitViolation_Reasons(t *testing.T) {
+	pr := types.Principal{Type: types.PrincipalCustomerUser, ID: "c1", Groups: []string{"customer:acme"}}
+	base := func() map[string]any {
+		return map[string]any{"project_id": "p1", "is_active": true, "deleted": false, "acl_allow": []any{"customer:acme"}}
+	}
+	cases := map[string]func(map[string]any){
+		"":                     func(map[string]any) {},
+		"project_out_of_scope": func(p map[string]any) { p["project_id"] = "p9" },
+		"inactive_version":     func(p map[string]any) { p["is_active"] = false },
+		"deleted":              func(p map[string]any) { delete(p, "deleted") },
+		"acl_denied":           func(p map[string]any) { p["acl_allow"] = []any{"customer:beta"}; p["acl_public"] = true },
+	}
+	for want, mutate := range cases {
+		p := base()
+		mutate(p)
+		if got := hitViolation(pr, []string{"p1"}, p); got != want {
+			t.Fatalf("expected %q, got %q for %v", want, got, p)
+		}
+	}
+}
+
+// evalFilter runs f through the evaluator behind the in-memory store.
+func evalFilter(f qdrant.Filter, id any, payload map[string]any) bool {
+	ok, err := qdrant.Eval(f, id, payload)
+	if err != nil {
+		panic(err)
+	}
+	return ok
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/llm"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+type answerRequest struct {
+	Query          string          `json:"query"`
+	ProjectScope   []string        `json:"project_scope"`
+	Principal      types.Principal `json:"principal"`
+	TopK           int             `json:"top_k"`
+	Filter         *searchFilter   `json:"filter"`
+	ScoreThreshold *float64        `json:"score_threshold"`
+}
+
+// citation maps a numbered source in the prompt back to the chunk it came from.
+type citation struct {
+	Index      int     `json:"index"`
+	ProjectID  string  `json:"project_id"`
+	DocID      string  `json:"doc_id"`
+	DocVersion string  `json:"doc_version"`
+	ChunkID    int     `json:"chunk_id"`
+	Title      string  `json:"title"`
+	PathOrURL  string  `json:"path_or_url"`
+	Score      float64 `json:"score"`
+	Cited      bool    `json:"cited"`
+}
+
+type answerResponse struct {
+	Answer        string     `json:"answer"`
+	Refused       bool       `json:"refused"`
+	RefusalReason string     `json:"refusal_reason,omitempty"`
+	Citations     []citation `json:"citations"`
+	Model         string     `json:"model,omitempty"`
+	Usage         *llm.Usage `json:"usage,omitempty"`
+	LatencyMS     int64      `json:"latency_ms"`
+}
+
+const answerSystemPrompt = `You are a knowledge base assistant. Answer the question using only the numbered sources provided.
+Cite every statement with the source number in square brackets, e.g. [1] or [2][3].
+If the sources do not contain the answer, say that you don't know. Never use outside knowledge.`
+
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	req, ok := s.decodeAnswerRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sources, apiErr := s.retrieveSources(r.Context(), req)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	if len(sources) == 0 {
+		writeJSON(w, http.StatusOK, answerResponse{
+			Refused:       true,
+			RefusalReason: "no_permitted_context",
+			Citations:     []citation{},
+			LatencyMS:     time.Since(start).Milliseconds(),
+		})
+		return
+	}
+
+	comp, err := s.llm.Complete(r.Context(), buildAnswerPrompt(req.Query, sources))
+	if err != nil {
+		log.Printf("answer: llm completion failed: %v", err)
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "llm_failed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, answerResponse{
+		Answer:    comp.Content,
+		Citations: markCited(buildCitations(sources), comp.Content),
+		Model:     comp.Model,
+		Usage:     &comp.Usage,
+		LatencyMS: time.Since(start).Milliseconds(),
+	})
+}
+
+func (s *Server) decodeAnswerRequest(w http.ResponseWriter, r *http.Request) (answerRequest, bool) {
+	var req answerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return req, false
+	}
+	if strings.TrimSpace(req.Query) == "" || len(req.ProjectScope) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
+		return req, false
+	}
+	pr, scope, apiErr := s.searchPrincipal(r, req.Principal, req.ProjectScope)
+	if apiErr != nil {
+		apiErr.write(w)
+		return req, false
+	}
+	req.Principal = pr
+	req.ProjectScope = scope
+	return req, true
+}
+
+// retrieveSources runs the regular ACL-filtered search and trims the hits to
+// the configured prompt context budget.
+func (s *Server) retrieveSources(ctx context.Context, req answerRequest) ([]searchResult, *apiError) {
+	topK := req.TopK
+	if topK <= 0 {
+		topK = 5
+	}
+	res, apiErr := s.search(ctx, searchRequest{
+		Query:          req.Query,
+		ProjectScope:   req.ProjectScope,
+		Principal:      req.Principal,
+		TopK:           topK,
+		Filter:         req.Filter,
+		ScoreThreshold: req.ScoreThreshold,
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	budget := s.cfg.LLM.MaxContextChars
+	out := make([]searchResult, 0, len(res.Results))
+	used := 0
+	for _, hit := range res.Results {
+		// Tagged spans never reach the model, whoever is asking.
+		hit.Text = s.redactor.MaskAll(hit.rawText, hit.redactions)
+		if strings.TrimSpace(hit.Text) == "" {
+			continue
+		}
+		if budget > 0 && len(out) > 0 && used+len(hit.Text) > budget {
+			break
+		}
+		used += len(hit.Text)
+		out = append(out, hit)
+	}
+	return out, nil
+}
+
+func buildAnswerPrompt(query string, sources []searchResult) []llm.Message {
+	var b strings.Builder
+	b.WriteString("Sources:\n\n")
+	for i, src := range sources {
+		fmt.Fprintf(&b, "[%d] %s", i+1, src.Title)
+		if src.PathOrURL != "" {
+			fmt.Fprintf(&b, " (%s)", src.PathOrURL)
+		}
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(src.Text))
+		b.WriteString("\n\n")
+	}
+	b.WriteString("Question: ")
+	b.WriteString(strings.TrimSpace(query))
+	return []llm.Message{
+		{Role: "system", Content: answerSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+func buildCitations(sources []searchResult) []citation {
+	out := make([]citation, 0, len(sources))
+	for i, src := range sources {
+		out = append(out, citation{
+			Index:      i + 1,
+			ProjectID:  src.ProjectID,
+			DocID:      src.DocID,
+			DocVersion: src.DocVersion,
+			ChunkID:    src.ChunkID,
+			Title:      src.Title,
+			PathOrURL:  src.PathOrURL,
+			Score:      src.Score,
+		})
+	}
+	return out
+}
+
+var citationRef = regexp.MustCompile(`\[(\d+)\]`)
+
+// markCited flags the citations the answer actually references.
+func markCited(cites []citation, answer string) []citation {
+	for _, m := range citationRef.FindAllStringSubmatch(answer, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n >= 1 && n <= len(cites) {
+			cites[n-1].Cited = true
+		}
+	}
+	return cites
+}
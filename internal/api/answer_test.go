@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildAnswerPrompt_NumbersSources(t *testing.T) {
+	sources := []searchResult{
+		{Title: "Doc A", PathOrURL: "a.md", Text: "Alpha text."},
+		{Title: "Doc B", Text: "Beta text."},
+	}
+	msgs := buildAnswerPrompt("what is alpha?", sources)
+	if len(msgs) != 2 || msgs[0].Role != "system" || msgs[1].Role != "user" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	user := msgs[1].Content
+	for _, want := range []string{"[1] Doc A (a.md)\nAlpha text.", "[2] Doc B\nBeta text.", "Question: what is alpha?"} {
+		if !strings.Contains(user, want) {
+			t.Fatalf("expected %q in prompt:\n%s", want, user)
+		}
+	}
+}
+
+func TestMarkCited(t *testing.T) {
+	cites := buildCitations([]searchResult{{DocID: "a"}, {DocID: "b"}, {DocID: "c"}})
+	cites = markCited(cites, "Alpha [1], gamma [3][9].")
+	if !cites[0].Cited || cites[1].Cited || !cites[2].Cited {
+		t.Fatalf("unexpected cited flags: %+v", cites)
+	}
+	if cites[2].Index != 3 || cites[2].DocID != "c" {
+		t.Fatalf("unexpected citation mapping: %+v", cites[2])
+	}
+}
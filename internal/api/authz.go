@@ -0,0 +1,127 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/auth"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// authorize checks that the caller holds at least min on projectID.
+// API key callers were already checked against the key's scopes and project
+// by requireScope. Without a policy file every caller is allowed only while
+// JWT authentication is off (local dev); with it on, nothing grants an
+// authenticated principal a role, so the check fails closed.
+func (s *Server) authorize(r *http.Request, projectID string, min rbac.Role) *apiError {
+	if _, ok := apikey.FromContext(r.Context()); ok {
+		return nil
+	}
+	if s.rbac == nil && s.auth == nil {
+		return nil
+	}
+	pr, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return &apiError{Status: http.StatusUnauthorized, Code: "unauthorized"}
+	}
+	if s.rbac == nil {
+		log.Printf("rbac: denied %s %s role=%s project=%s %s %s: no policy loaded (request %s)", pr.Type, pr.ID, min, projectID, r.Method, r.URL.Path, requestID(r))
+		return &apiError{Status: http.StatusForbidden, Code: "forbidden"}
+	}
+	pr = s.expandGroups(pr)
+	if !s.rbac.Allowed(pr, projectID, min) {
+		log.Printf("rbac: denied %s %s role=%s project=%s %s %s (request %s)", pr.Type, pr.ID, min, projectID, r.Method, r.URL.Path, requestID(r))
+		return &apiError{Status: http.StatusForbidden, Code: "forbidden"}
+	}
+	return nil
+}
+
+// actor identifies the caller for audit records.
+func (s *Server) actor(r *http.Request) types.Principal {
+	if k, ok := apikey.FromContext(r.Context()); ok {
+		return types.Principal{Type: types.PrincipalService, ID: "apikey:" + k.ID}
+	}
+	if pr, ok := auth.PrincipalFrom(r.Context()); ok {
+		return pr
+	}
+	return anonymousPrincipal
+}
+
+// anonymousPrincipal acts for unauthenticated callers in local dev.
+var anonymousPrincipal = types.Principal{Type: types.PrincipalService, ID: "anonymous"}
+
+// authorizePrincipal is authorize for actions that must be attributable to a
+// principal, such as publishing: API keys are rejected and the caller must be
+// authenticated unless the gateway runs without any auth configured (local dev).
+func (s *Server) authorizePrincipal(r *http.Request, projectID string, min rbac.Role) (types.Principal, *apiError) {
+	if _, ok := apikey.FromContext(r.Context()); ok {
+		return types.Principal{}, &apiError{Status: http.StatusForbidden, Code: "principal_required"}
+	}
+	pr, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		if s.auth == nil && s.apiKeys == nil && s.rbac == nil {
+			return anonymousPrincipal, nil
+		}
+		return types.Principal{}, &apiError{Status: http.StatusUnauthorized, Code: "unauthorized"}
+	}
+	if apiErr := s.authorize(r, projectID, min); apiErr != nil {
+		return types.Principal{}, apiErr
+	}
+	return s.expandGroups(pr), nil
+}
+
+// searchPrincipal resolves the principal for a search-like request and
+// narrows the requested scope to the projects it may read. Projects the
+// principal is not a member of are dropped; if none remain the request is
+// rejected instead of silently searching nothing.
+func (s *Server) searchPrincipal(r *http.Request, body types.Principal, scope []string) (types.Principal, []string, *apiError) {
+	scope = normalizeScope(scope)
+	if len(scope) == 0 {
+		return types.Principal{}, nil, &apiError{Status: http.StatusBadRequest, Code: "empty_project_scope"}
+	}
+	pr, apiErr := s.effectivePrincipal(r, body)
+	if apiErr != nil {
+		return types.Principal{}, nil, apiErr
+	}
+	if s.rbac == nil {
+		// Customers only read projects they are members of, and without a
+		// policy they are members of none.
+		if s.auth != nil && pr.Type == types.PrincipalCustomerUser {
+			log.Printf("rbac: denied %s %s search projects=%v: no policy loaded (request %s)", pr.Type, pr.ID, scope, requestID(r))
+			return types.Principal{}, nil, &apiError{Status: http.StatusForbidden, Code: "project_scope_forbidden"}
+		}
+		return pr, scope, nil
+	}
+	// A key acting as itself is bound to its project by requireScope; an
+	// impersonated principal is checked like any other.
+	if k, ok := apikey.FromContext(r.Context()); ok && pr.ID == "apikey:"+k.ID {
+		return pr, scope, nil
+	}
+	allowed := s.rbac.AllowedProjects(pr, scope)
+	if len(allowed) == 0 {
+		log.Printf("rbac: denied %s %s search projects=%v (request %s)", pr.Type, pr.ID, scope, requestID(r))
+		return types.Principal{}, nil, &apiError{Status: http.StatusForbidden, Code: "project_scope_forbidden"}
+	}
+	if len(allowed) < len(scope) {
+		log.Printf("rbac: narrowed %s %s search projects=%v to %v (request %s)", pr.Type, pr.ID, scope, allowed, requestID(r))
+	}
+	return pr, allowed, nil
+}
+
+// normalizeScope trims, de-duplicates and drops empty project IDs.
+func normalizeScope(scope []string) []string {
+	seen := make(map[string]bool, len(scope))
+	out := make([]string, 0, len(scope))
+	for _, p := range scope {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
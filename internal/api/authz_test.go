@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/auth"
+	"github.com/HardMakabaka/KB-Gateway/internal/groups"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func TestSearchPrincipal_IntersectsScope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"projects":{"proj1":{"groups":{"customer:acme":"reader"}},"*":{"groups":{"staff":"reader"}}}}`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := rbac.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{rbac: a}
+	req := httptest.NewRequest(http.MethodPost, "/v1/search", nil)
+	customer := types.Principal{Type: types.PrincipalCustomerUser, ID: "c1", Groups: []string{"customer:acme", "staff"}}
+
+	_, scope, apiErr := s.searchPrincipal(req, customer, []string{"proj1", "proj2", " proj1 "})
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %+v", apiErr)
+	}
+	if len(scope) != 1 || scope[0] != "proj1" {
+		t.Fatalf("expected scope narrowed to proj1, got %v", scope)
+	}
+
+	_, _, apiErr = s.searchPrincipal(req, customer, []string{"proj2"})
+	if apiErr == nil || apiErr.Status != http.StatusForbidden {
+		t.Fatalf("expected 403 for fully disallowed scope, got %+v", apiErr)
+	}
+
+	_, _, apiErr = s.searchPrincipal(req, customer, []string{"", "  "})
+	if apiErr == nil || apiErr.Code != "empty_project_scope" {
+		t.Fatalf("expected empty_project_scope, got %+v", apiErr)
+	}
+}
+
+func TestSearchPrincipal_ExpandsGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(`{"projects":{"proj1":{"groups":{"customer:acme":"reader"}}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := rbac.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := groups.NewResolver(groups.Config{Hierarchy: true, CacheTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{rbac: a, groups: resolver}
+	req := httptest.NewRequest(http.MethodPost, "/v1/search", nil)
+	customer := types.Principal{Type: types.PrincipalCustomerUser, ID: "c1", Groups: []string{"customer:acme:support"}}
+
+	pr, scope, apiErr := s.searchPrincipal(req, customer, []string{"proj1"})
+	if apiErr != nil {
+		t.Fatalf("expected customer:acme:support to inherit customer:acme, got %+v", apiErr)
+	}
+	if len(scope) != 1 || len(pr.Groups) != 2 || pr.Groups[1] != "customer:acme" {
+		t.Fatalf("unexpected principal %+v scope %v", pr, scope)
+	}
+}
+
+func TestAuthorize_FailsClosedWithoutPolicy(t *testing.T) {
+	s := &Server{auth: auth.NewAuthenticator(nil, "", "", nil)}
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/migrations", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), internal))
+
+	if apiErr := s.authorize(req, rbac.AllProjects, rbac.RoleAdmin); apiErr == nil || apiErr.Status != http.StatusForbidden {
+		t.Fatalf("expected 403 with JWT auth and no policy, got %+v", apiErr)
+	}
+	if _, apiErr := s.authorizePrincipal(req, "proj1", rbac.RolePublisher); apiErr == nil || apiErr.Status != http.StatusForbidden {
+		t.Fatalf("expected 403 for publishing with JWT auth and no policy, got %+v", apiErr)
+	}
+
+	s.auth = nil
+	if apiErr := s.authorize(req, rbac.AllProjects, rbac.RoleAdmin); apiErr != nil {
+		t.Fatalf("without any auth role checks stay open for local dev, got %+v", apiErr)
+	}
+}
+
+func TestSearchPrincipal_CustomersNeedPolicy(t *testing.T) {
+	s := &Server{auth: auth.NewAuthenticator(nil, "", "", nil)}
+	customer := types.Principal{Type: types.PrincipalCustomerUser, ID: "c1", Groups: []string{"customer:acme"}}
+	req := httptest.NewRequest(http.MethodPost, "/v1/search", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), customer))
+
+	if _, _, apiErr := s.searchPrincipal(req, types.Principal{}, []string{"proj1"}); apiErr == nil || apiErr.Code != "project_scope_forbidden" {
+		t.Fatalf("expected customers to be denied without a policy, got %+v", apiErr)
+	}
+
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), internal))
+	if _, scope, apiErr := s.searchPrincipal(req, types.Principal{}, []string{"proj1"}); apiErr != nil || len(scope) != 1 {
+		t.Fatalf("internal users keep their scope without a policy, got %v %+v", scope, apiErr)
+	}
+}
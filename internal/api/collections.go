@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Blue/green collections: the alias is the only name requests use, so an
+// admin can build a new physical collection next to the live one (see
+// migrate.go), switch the alias to it, and drop the old one later.
+
+type collectionInfo struct {
+	Name       string `json:"name"`
+	VectorSize int    `json:"vector_size"`
+	Distance   string `json:"distance"`
+	Points     int    `json:"points"`
+	Current    bool   `json:"current"`
+}
+
+type buildCollectionRequest struct {
+	// Name of the new collection; defaults to <collection>_<timestamp>.
+	Name string `json:"name"`
+	// Mode is "copy" (keep the stored vectors) or "rebuild" (re-embed every
+	// chunk with the current embedder).
+	Mode string `json:"mode"`
+}
+
+type switchAliasRequest struct {
+	Collection string `json:"collection"`
+}
+
+func (s *Server) handleListCollections(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	as, ok := s.store.aliases()
+	if s.cfg.Qdrant.Alias == "" || !ok {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "alias_required"})
+		return
+	}
+	current, err := as.ResolveAlias(r.Context(), s.cfg.Qdrant.Alias)
+	if err != nil {
+		storeError("resolve_alias_failed", err).write(w)
+		return
+	}
+	names, err := as.ListCollections(r.Context())
+	if err != nil {
+		storeError("list_collections_failed", err).write(w)
+		return
+	}
+	out := make([]collectionInfo, 0, len(names))
+	for _, name := range names {
+		info, err := s.store.CollectionInfo(r.Context(), name)
+		if err != nil {
+			storeError("collection_info_failed", err).write(w)
+			return
+		}
+		n, err := s.store.Count(r.Context(), name, nil)
+		if err != nil {
+			storeError("count_failed", err).write(w)
+			return
+		}
+		out = append(out, collectionInfo{Name: name, VectorSize: info.VectorSize, Distance: info.Distance, Points: n, Current: name == current})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"alias": s.cfg.Qdrant.Alias, "current": current, "collections": out})
+}
+
+func (s *Server) handleBuildCollection(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	var req buildCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	spec := migrationStatus{Target: req.Name}
+	emb := s.embedder.Current()
+	switch req.Mode {
+	case "copy":
+		info, err := s.store.CollectionInfo(r.Context(), s.collection)
+		if err != nil {
+			storeError("collection_info_failed", err).write(w)
+			return
+		}
+		spec.Mode, spec.Dim, emb = "copy", info.VectorSize, nil
+	case "rebuild":
+		spec.Mode, spec.Dim = "reembed", emb.Dim()
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_mode", "detail": "mode must be copy or rebuild"})
+		return
+	}
+	job, apiErr := s.startJob(r, spec, emb, false)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+// handleSwitchAlias points the alias at a collection. A collection built by
+// the current job is switched to by the job, which stops mirroring at the
+// same moment; any other collection must match the embedder and is switched
+// to as is, e.g. to roll back to the previous one.
+func (s *Server) handleSwitchAlias(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	var req switchAliasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	alias := s.cfg.Qdrant.Alias
+	as, ok := s.store.aliases()
+	if alias == "" || !ok {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "alias_required"})
+		return
+	}
+	if req.Collection == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields", "detail": "collection is required"})
+		return
+	}
+
+	if job := s.migration.active(); job != nil {
+		st := job.snapshot()
+		if st.State != migrationReady || st.Target != req.Collection {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "migration_running", "detail": fmt.Sprintf("%s is building %s", st.ID, st.Target)})
+			return
+		}
+		reply := make(chan error, 1)
+		select {
+		case job.flip <- reply:
+		case <-job.done:
+			writeJSON(w, http.StatusConflict, map[string]any{"error": "migration_not_running"})
+			return
+		}
+		if err := <-reply; err != nil {
+			storeError("switch_alias_failed", err).write(w)
+			return
+		}
+		<-job.done
+		writeJSON(w, http.StatusOK, map[string]any{"alias": alias, "collection": req.Collection})
+		return
+	}
+
+	names, err := as.ListCollections(r.Context())
+	if err != nil {
+		storeError("list_collections_failed", err).write(w)
+		return
+	}
+	if !slices.Contains(names, req.Collection) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "collection_not_found"})
+		return
+	}
+	info, err := s.store.CollectionInfo(r.Context(), req.Collection)
+	if err != nil {
+		storeError("collection_info_failed", err).write(w)
+		return
+	}
+	if dim := s.embedder.Dim(); info.VectorSize != dim {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "collection_mismatch", "detail": fmt.Sprintf("collection has %d-dim vectors, embedder needs %d", info.VectorSize, dim)})
+		return
+	}
+	unlock := s.docLocks.LockAll()
+	err = as.SwitchAlias(r.Context(), alias, req.Collection)
+	unlock()
+	if err != nil {
+		storeError("switch_alias_failed", err).write(w)
+		return
+	}
+	log.Printf("alias %s now points at %s", alias, req.Collection)
+	writeJSON(w, http.StatusOK, map[string]any{"alias": alias, "collection": req.Collection})
+}
+
+func (s *Server) handleDeleteCollection(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	name := chi.URLParam(r, "name")
+	as, ok := s.store.aliases()
+	if s.cfg.Qdrant.Alias == "" || !ok {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "alias_required"})
+		return
+	}
+	current, err := as.ResolveAlias(r.Context(), s.cfg.Qdrant.Alias)
+	if err != nil {
+		storeError("resolve_alias_failed", err).write(w)
+		return
+	}
+	if name == current {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "collection_in_use"})
+		return
+	}
+	if job := s.migration.active(); job != nil && job.snapshot().Target == name {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "migration_running"})
+		return
+	}
+	names, err := as.ListCollections(r.Context())
+	if err != nil {
+		storeError("list_collections_failed", err).write(w)
+		return
+	}
+	if !slices.Contains(names, name) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "collection_not_found"})
+		return
+	}
+	if err := as.DeleteCollection(r.Context(), name); err != nil {
+		storeError("delete_collection_failed", err).write(w)
+		return
+	}
+	log.Printf("dropped collection %s", name)
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": name})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func doMethod(t *testing.T, h http.Handler, method, path string, body, out any) int {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(b)))
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestCollections_BlueGreenSwitch(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	h := newAliasServer(t, st)
+	ingest := func(doc string) {
+		req := ingestRequest{ProjectID: "proj1", DocID: doc, Content: "blue green " + doc, ACLPublic: true}
+		if code := doJSON(t, h, "/v1/docs/ingest", req, nil); code != http.StatusOK {
+			t.Fatalf("ingest %s: status %d", doc, code)
+		}
+	}
+	ingest("doc1")
+
+	build := buildCollectionRequest{Name: "kb_green", Mode: "copy"}
+	if code := doJSON(t, h, "/v1/admin/collections", build, nil); code != http.StatusAccepted {
+		t.Fatalf("build: status %d", code)
+	}
+	waitMigration(t, h, migrationReady)
+	ingest("doc2")
+	blue, _ := st.Count(ctx, "kb_chunks", nil)
+	if green, _ := st.Count(ctx, "kb_green", nil); green != blue {
+		t.Fatalf("a ready collection must keep receiving writes: %d vs %d points", green, blue)
+	}
+
+	var list struct {
+		Current     string           `json:"current"`
+		Collections []collectionInfo `json:"collections"`
+	}
+	if code := doMethod(t, h, http.MethodGet, "/v1/admin/collections", nil, &list); code != http.StatusOK || list.Current != "kb_chunks" || len(list.Collections) != 2 {
+		t.Fatalf("list: status %d %+v", code, list)
+	}
+	if code := doMethod(t, h, http.MethodDelete, "/v1/admin/collections/kb_chunks", nil, nil); code != http.StatusConflict {
+		t.Fatalf("deleting the live collection must be refused, got %d", code)
+	}
+
+	if code := doJSON(t, h, "/v1/admin/alias", switchAliasRequest{Collection: "kb_green"}, nil); code != http.StatusOK {
+		t.Fatalf("switch: status %d", code)
+	}
+	if got, _ := st.ResolveAlias(ctx, "kb"); got != "kb_green" {
+		t.Fatalf("expected kb -> kb_green, got %q", got)
+	}
+	waitMigration(t, h, migrationCompleted)
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	if res := searchAs(t, h, internal, "blue green"); len(res) != 2 {
+		t.Fatalf("expected both docs after the switch, got %+v", res)
+	}
+
+	ingest("doc3")
+	if code := doJSON(t, h, "/v1/admin/alias", switchAliasRequest{Collection: "kb_chunks"}, nil); code != http.StatusOK {
+		t.Fatalf("switch back: status %d", code)
+	}
+	if code := doMethod(t, h, http.MethodDelete, "/v1/admin/collections/kb_green", nil, nil); code != http.StatusOK {
+		t.Fatalf("delete: status %d", code)
+	}
+	if names, _ := st.ListCollections(ctx); len(names) != 1 || names[0] != "kb_chunks" {
+		t.Fatalf("expected only kb_chunks left, got %v", names)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+)
+
+func TestRequireScope(t *testing.T) {
+	store, err := apikey.Open(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ingestKey, _ := store.Issue("proj1", []apikey.Scope{apikey.ScopeIngest}, "")
+	s := &Server{cfg: config.Config{Limits: config.LimitsConfig{MaxContentBytes: 1 << 20}}, apiKeys: store}
+
+	var seenBody string
+	h := s.authenticate(s.requireScope(apikey.ScopeIngest)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seenBody = string(b)
+	})))
+	del := s.authenticate(s.requireScope(apikey.ScopeDelete)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+
+	cases := []struct {
+		name    string
+		handler http.Handler
+		key     string
+		body    string
+		want    int
+	}{
+		{"no key", h, "", `{"project_id":"proj1"}`, http.StatusUnauthorized},
+		{"bad key", h, "kbg_nope_nope", `{"project_id":"proj1"}`, http.StatusUnauthorized},
+		{"other project", h, ingestKey, `{"project_id":"proj2"}`, http.StatusForbidden},
+		{"missing scope", del, ingestKey, `{"project_id":"proj1"}`, http.StatusForbidden},
+		{"ok", h, ingestKey, `{"project_id":"proj1","content":"x"}`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/docs/ingest", strings.NewReader(tc.body))
+		if tc.key != "" {
+			req.Header.Set("X-API-Key", tc.key)
+		}
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d (%s)", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+	if seenBody != `{"project_id":"proj1","content":"x"}` {
+		t.Fatalf("handler should see the original body, got %q", seenBody)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// maxSearchOffset bounds deep paging; Qdrant cost grows with offset+limit.
+const maxSearchOffset = 1000
+
+// searchCursor is the opaque next_cursor returned by /v1/search. It carries
+// the hash of the query embedding so later pages can reuse the cached vector
+// instead of re-embedding, plus a fingerprint of the search parameters so a
+// cursor cannot be replayed against a different scope or filter, or by
+// another principal.
+type searchCursor struct {
+	EmbeddingHash string `json:"h"`
+	Offset        int    `json:"o"`
+	Fingerprint   string `json:"f"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+func encodeCursor(c searchCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (searchCursor, error) {
+	var c searchCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.EmbeddingHash == "" || c.Offset < 0 {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
+
+// searchFingerprint hashes every request field that shapes the result list,
+// except the query itself (covered by the embedding hash) and the page offset.
+// It includes the effective principal, so a cursor cannot page through
+// results ranked by another caller's query.
+func searchFingerprint(req searchRequest, limit int) string {
+	groups := append([]string(nil), req.Principal.Groups...)
+	sort.Strings(groups)
+	b, _ := json.Marshal(struct {
+		PrincipalType  types.PrincipalType `json:"pt"`
+		PrincipalID    string              `json:"pi"`
+		Groups         []string            `json:"g"`
+		Scope          []string            `json:"s"`
+		Filter         *searchFilter       `json:"f"`
+		ScoreThreshold *float64            `json:"t"`
+		Limit          int                 `json:"l"`
+	}{req.Principal.Type, req.Principal.ID, groups, req.ProjectScope, req.Filter, req.ScoreThreshold, limit})
+	return hashString(string(b))[:16]
+}
+
+func hashVector(v []float32) string {
+	h := sha256.New()
+	var buf [4]byte
+	for _, x := range v {
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(x))
+		h.Write(buf[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// vectorCache keeps recent query embeddings keyed by hashVector, evicting the
+// oldest entry once full.
+type vectorCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[string][]float32
+	order []string
+}
+
+func newVectorCache(size int) *vectorCache {
+	return &vectorCache{size: size, items: make(map[string][]float32, size)}
+}
+
+func (c *vectorCache) Get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.items[key]
+	return v, ok
+}
+
+func (c *vectorCache) Put(key string, v []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	if len(c.order) >= c.size {
+		delete(c.items, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.items[key] = v
+	c.order = append(c.order, key)
+}
+
+// Reset drops every cached vector, e.g. after the embedding model changed.
+func (c *vectorCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string][]float32, c.size)
+	c.order = nil
+}
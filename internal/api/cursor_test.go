@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := searchCursor{EmbeddingHash: hashVector([]float32{0.1, 0.2}), Offset: 20, Fingerprint: "abc"}
+	got, err := decodeCursor(encodeCursor(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != c {
+		t.Fatalf("got %+v want %+v", got, c)
+	}
+	for _, bad := range []string{"", "not-base64!", encodeCursor(searchCursor{Offset: 1})} {
+		if _, err := decodeCursor(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestSearchFingerprint_IgnoresQueryAndOffset(t *testing.T) {
+	a := searchRequest{Query: "a", ProjectScope: []string{"p1"}, Offset: 0}
+	b := searchRequest{Query: "b", ProjectScope: []string{"p1"}, Offset: 10}
+	if searchFingerprint(a, 10) != searchFingerprint(b, 10) {
+		t.Fatalf("expected query and offset not to affect the fingerprint")
+	}
+	threshold := 0.5
+	c := searchRequest{Query: "a", ProjectScope: []string{"p1"}, ScoreThreshold: &threshold}
+	if searchFingerprint(a, 10) == searchFingerprint(c, 10) {
+		t.Fatalf("expected score_threshold to change the fingerprint")
+	}
+	d := searchRequest{Query: "a", ProjectScope: []string{"p2"}}
+	if searchFingerprint(a, 10) == searchFingerprint(d, 10) {
+		t.Fatalf("expected project_scope to change the fingerprint")
+	}
+}
+
+func TestSearchFingerprint_BindsPrincipal(t *testing.T) {
+	alice := types.Principal{Type: types.PrincipalInternalUser, ID: "alice", Groups: []string{"eng", "ops"}}
+	a := searchRequest{ProjectScope: []string{"p1"}, Principal: alice}
+	reordered := a
+	reordered.Principal.Groups = []string{"ops", "eng"}
+	if searchFingerprint(a, 10) != searchFingerprint(reordered, 10) {
+		t.Fatalf("expected group order not to affect the fingerprint")
+	}
+	for _, pr := range []types.Principal{
+		{Type: types.PrincipalInternalUser, ID: "bob", Groups: alice.Groups},
+		{Type: types.PrincipalCustomerUser, ID: "alice", Groups: alice.Groups},
+		{Type: types.PrincipalInternalUser, ID: "alice", Groups: []string{"eng"}},
+	} {
+		b := a
+		b.Principal = pr
+		if searchFingerprint(a, 10) == searchFingerprint(b, 10) {
+			t.Fatalf("expected principal %+v to change the fingerprint", pr)
+		}
+	}
+}
+
+func TestSearch_CursorRejectsOtherPrincipal(t *testing.T) {
+	h, _ := newTestServer(t)
+	for _, doc := range []string{"doc1", "doc2", "doc3"} {
+		if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: doc, Content: "paging handbook " + doc, ACLPublic: true}, nil); code != http.StatusOK {
+			t.Fatalf("ingest %s: status %d", doc, code)
+		}
+	}
+	alice := types.Principal{Type: types.PrincipalInternalUser, ID: "alice"}
+	bob := types.Principal{Type: types.PrincipalInternalUser, ID: "bob"}
+	var first searchResponse
+	req := searchRequest{Query: "paging handbook", ProjectScope: []string{"proj1"}, Principal: alice, TopK: 1}
+	if code := doJSON(t, h, "/v1/search", req, &first); code != http.StatusOK || first.NextCursor == "" {
+		t.Fatalf("first page: status %d %+v", code, first)
+	}
+
+	next := searchRequest{Cursor: first.NextCursor, ProjectScope: []string{"proj1"}, Principal: bob, TopK: 1}
+	if code := doJSON(t, h, "/v1/search", next, nil); code != http.StatusBadRequest {
+		t.Fatalf("another principal's replay must be rejected, got %d", code)
+	}
+	next.Principal = alice
+	if code := doJSON(t, h, "/v1/search", next, nil); code != http.StatusOK {
+		t.Fatalf("the owner's next page: status %d", code)
+	}
+}
+
+func TestVectorCache_EvictsOldest(t *testing.T) {
+	c := newVectorCache(2)
+	c.Put("a", []float32{1})
+	c.Put("b", []float32{2})
+	c.Put("c", []float32{3})
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("expected oldest entry to be evicted")
+	}
+	if v, ok := c.Get("c"); !ok || v[0] != 3 {
+		t.Fatalf("expected newest entry to be cached")
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/audit"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+type docACLRequest struct {
+	ProjectID string `json:"project_id"`
+	DocID     string `json:"doc_id"`
+	// DocVersions limits the update to these versions; empty means all versions.
+	DocVersions       []string  `json:"doc_versions"`
+	ACLPublic         *bool     `json:"acl_public"`
+	ACLAllow          *[]string `json:"acl_allow"`
+	ACLExternalPublic *bool     `json:"acl_external_public"`
+	ACLDeny           *[]string `json:"acl_deny"`
+}
+
+var groupNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:@/-]{0,127}$`)
+
+const maxACLGroups = 256
+
+func validateGroups(groups []string) error {
+	if len(groups) > maxACLGroups {
+		return fmt.Errorf("more than %d groups", maxACLGroups)
+	}
+	for _, g := range groups {
+		if !groupNameRe.MatchString(g) {
+			return fmt.Errorf("invalid group name %q", g)
+		}
+	}
+	return nil
+}
+
+// handleDocACL updates ACL fields on existing versions of a document in
+// place, without re-ingesting. It changes who may read the document, so it
+// needs the publisher role (or an API key with the admin scope). Changing
+// acl_external_public bypasses the publish request/approve steps, so it also
+// needs a principal rather than an API key.
+func (s *Server) handleDocACL(w http.ResponseWriter, r *http.Request) {
+	var req docACLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	if req.ProjectID == "" || req.DocID == "" || (req.ACLPublic == nil && req.ACLAllow == nil && req.ACLExternalPublic == nil && req.ACLDeny == nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
+		return
+	}
+	for _, groups := range []*[]string{req.ACLAllow, req.ACLDeny} {
+		if groups == nil {
+			continue
+		}
+		if err := validateGroups(*groups); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_acl", "detail": err.Error()})
+			return
+		}
+	}
+
+	var actor types.Principal
+	if req.ACLExternalPublic != nil {
+		pr, apiErr := s.authorizePrincipal(r, req.ProjectID, rbac.RolePublisher)
+		if apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+		actor = pr
+	} else {
+		if apiErr := s.authorize(r, req.ProjectID, rbac.RolePublisher); apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+		actor = s.actor(r)
+	}
+
+	unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
+	defer unlock()
+
+	f := qdrant.Filter{"must": []any{
+		matchValue("project_id", req.ProjectID),
+		matchValue("doc_id", req.DocID),
+	}}
+	if len(req.DocVersions) > 0 {
+		f["must"] = append(f["must"].([]any), matchAny("doc_version", req.DocVersions))
+	}
+
+	before, err := s.versionACLs(r.Context(), f)
+	if err != nil {
+		storeError("qdrant_scroll_failed", err).write(w)
+		return
+	}
+	if len(before) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "doc_not_found"})
+		return
+	}
+	var missing []string
+	for _, v := range req.DocVersions {
+		if _, ok := before[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "version_not_found", "doc_versions": missing})
+		return
+	}
+
+	now := time.Now().UTC().Unix()
+	patch := map[string]any{"updated_at": now}
+	if req.ACLPublic != nil {
+		patch["acl_public"] = *req.ACLPublic
+	}
+	if req.ACLAllow != nil {
+		allow := *req.ACLAllow
+		if allow == nil {
+			allow = []string{}
+		}
+		patch["acl_allow"] = allow
+	}
+	if req.ACLDeny != nil {
+		deny := *req.ACLDeny
+		if deny == nil {
+			deny = []string{}
+		}
+		patch["acl_deny"] = deny
+	}
+	if req.ACLExternalPublic != nil {
+		patch["acl_external_public"] = *req.ACLExternalPublic
+		// Keep the publishing workflow state consistent with the direct change.
+		if *req.ACLExternalPublic {
+			patch["publish_status"] = publishApproved
+			patch["publish_approved_by"] = actor.ID
+			patch["publish_approved_at"] = now
+		} else {
+			patch["publish_status"] = publishRevoked
+			patch["publish_revoked_by"] = actor.ID
+			patch["publish_revoked_at"] = now
+		}
+	}
+
+	if err := s.store.SetPayload(r.Context(), s.collection, patch, f); err != nil {
+		storeError("qdrant_set_payload_failed", err).write(w)
+		return
+	}
+
+	versions := make([]string, 0, len(before))
+	for v := range before {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	for _, v := range versions {
+		after := map[string]any{}
+		for k, old := range before[v] {
+			after[k] = old
+			if nv, ok := patch[k]; ok {
+				after[k] = nv
+			}
+		}
+		s.audit.Record(audit.Event{
+			Action:     "doc.acl_update",
+			ActorType:  string(actor.Type),
+			ActorID:    actor.ID,
+			ProjectID:  req.ProjectID,
+			DocID:      req.DocID,
+			DocVersion: v,
+			Before:     before[v],
+			After:      after,
+			RequestID:  requestID(r),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "doc_versions": versions})
+}
+
+// versionACLs returns the ACL fields of every doc_version matching f, read
+// from the first point seen for each version.
+func (s *Server) versionACLs(ctx context.Context, f qdrant.Filter) (map[string]map[string]any, error) {
+	out := map[string]map[string]any{}
+	err := s.scrollAll(ctx, f, false, func(points []qdrant.ScoredPoint) error {
+		for _, pt := range points {
+			v := toString(pt.Payload["doc_version"])
+			if _, ok := out[v]; ok {
+				continue
+			}
+			out[v] = map[string]any{
+				"acl_public":          pt.Payload["acl_public"] == true,
+				"acl_allow":           toStrings(pt.Payload["acl_allow"]),
+				"acl_deny":            toStrings(pt.Payload["acl_deny"]),
+				"acl_external_public": pt.Payload["acl_external_public"] == true,
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// scrollAll pages through every point matching f.
+func (s *Server) scrollAll(ctx context.Context, f qdrant.Filter, withVector bool, fn func([]qdrant.ScoredPoint) error) error {
+	var offset any
+	for {
+		res, err := s.store.Scroll(ctx, s.collection, f, 256, offset, withVector)
+		if err != nil {
+			return err
+		}
+		if err := fn(res.Points); err != nil {
+			return err
+		}
+		if res.NextPageOffset == nil || len(res.Points) == 0 {
+			return nil
+		}
+		offset = res.NextPageOffset
+	}
+}
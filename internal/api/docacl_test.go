@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+func TestValidateGroups(t *testing.T) {
+	if err := validateGroups([]string{"eng", "customer:acme", "team/platform", "svc@bots", "a.b-c_d"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, bad := range []string{"", " eng", "eng team", ":acme", "ä", string(make([]byte, 200))} {
+		if err := validateGroups([]string{bad}); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestDocACL_UpdatesEveryVersion(t *testing.T) {
+	s, st, buf := newRoleServer(t)
+	allow := []string{"eng", "support"}
+	public := true
+	req := docACLRequest{ProjectID: "proj1", DocID: "doc1", ACLAllow: &allow, ACLPublic: &public}
+
+	if rec := callAs(t, s.handleDocACL, editor, req); rec.Code != http.StatusForbidden {
+		t.Fatalf("an editor must not change ACLs, got %d", rec.Code)
+	}
+	if rec := callAs(t, s.handleDocACL, publisher, req); rec.Code != http.StatusOK {
+		t.Fatalf("acl update: status %d %s", rec.Code, rec.Body.String())
+	}
+	res, err := st.Scroll(context.Background(), "kb_chunks", qdrant.Filter{"must": []any{matchValue("doc_id", "doc1")}}, 10, nil, false)
+	if err != nil || len(res.Points) != 3 {
+		t.Fatalf("scroll: %v %+v", err, res)
+	}
+	for _, p := range res.Points {
+		if !reflect.DeepEqual(toStrings(p.Payload["acl_allow"]), allow) || p.Payload["acl_public"] != true {
+			t.Fatalf("point %v of %v not updated: %+v", p.ID, p.Payload["doc_version"], p.Payload)
+		}
+	}
+
+	events := auditEvents(t, buf)
+	if len(events) != 2 || events[0].DocVersion != "v1" || events[1].DocVersion != "v2" {
+		t.Fatalf("expected one audit event per version, got %+v", events)
+	}
+	for _, e := range events {
+		if e.Action != "doc.acl_update" || e.ActorID != "bob" {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		if !reflect.DeepEqual(toStrings(e.Before["acl_allow"]), []string{"eng"}) || e.Before["acl_public"] != false {
+			t.Fatalf("before must hold the old ACL, got %+v", e.Before)
+		}
+		if !reflect.DeepEqual(toStrings(e.After["acl_allow"]), allow) || e.After["acl_public"] != true || e.After["acl_external_public"] != false {
+			t.Fatalf("after must hold the new ACL, got %+v", e.After)
+		}
+	}
+}
+
+func TestDocACL_ExternalPublicNeedsPublisher(t *testing.T) {
+	s, st, _ := newRoleServer(t)
+	on := true
+	req := docACLRequest{ProjectID: "proj1", DocID: "doc1", DocVersions: []string{"v2"}, ACLExternalPublic: &on}
+
+	if rec := callAs(t, s.handleDocACL, editor, req); rec.Code != http.StatusForbidden {
+		t.Fatalf("an editor must not publish externally, got %d", rec.Code)
+	}
+	if externalPublic(t, st, "v2") {
+		t.Fatal("a rejected update must not publish")
+	}
+	if rec := callAs(t, s.handleDocACL, publisher, req); rec.Code != http.StatusOK {
+		t.Fatalf("acl update: status %d %s", rec.Code, rec.Body.String())
+	}
+	if !externalPublic(t, st, "v2") || externalPublic(t, st, "v1") {
+		t.Fatal("only the selected version may be published")
+	}
+	if rec := callAs(t, s.handleDocACL, publisher, docACLRequest{ProjectID: "proj1", DocID: "doc1", DocVersions: []string{"v9"}, ACLExternalPublic: &on}); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown version, got %d", rec.Code)
+	}
+}
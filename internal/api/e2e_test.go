@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func newTestServer(t *testing.T) (http.Handler, *store.Memory) {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemory()
+	if err := st.EnsureCollection(context.Background(), cfg.Qdrant.Collection, 384); err != nil {
+		t.Fatal(err)
+	}
+	h := newServer(cfg, st)
+	waitHealth(t, h, "ok")
+	return h, st
+}
+
+func doJSON(t *testing.T, h http.Handler, path string, body, out any) int {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: decode %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func searchAs(t *testing.T, h http.Handler, pr types.Principal, query string) []searchResult {
+	t.Helper()
+	var resp searchResponse
+	req := searchRequest{Query: query, ProjectScope: []string{"proj1"}, Principal: pr, TopK: 10}
+	if code := doJSON(t, h, "/v1/search", req, &resp); code != http.StatusOK {
+		t.Fatalf("search: status %d", code)
+	}
+	return resp.Results
+}
+
+func TestE2E_IngestActivateRollbackDelete(t *testing.T) {
+	h, _ := newTestServer(t)
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+
+	var v1 ingestResponse
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Title: "Runbook", Content: "first revision", ACLPublic: true}, &v1); code != http.StatusOK {
+		t.Fatalf("ingest v1: status %d", code)
+	}
+	res := searchAs(t, h, internal, "first revision")
+	if len(res) != 1 || res[0].DocVersion != v1.DocVersion || res[0].Text != "first revision" {
+		t.Fatalf("expected v1 hit, got %+v", res)
+	}
+
+	// doc_version has second resolution.
+	time.Sleep(1100 * time.Millisecond)
+	var v2 ingestResponse
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Title: "Runbook", Content: "second revision", ACLPublic: true}, &v2); code != http.StatusOK {
+		t.Fatalf("ingest v2: status %d", code)
+	}
+	if res := searchAs(t, h, internal, "first revision"); len(res) != 1 || res[0].DocVersion != v2.DocVersion {
+		t.Fatalf("only the active version may be returned, got %+v", res)
+	}
+
+	if code := doJSON(t, h, "/v1/docs/rollback", rollbackRequest{ProjectID: "proj1", DocID: "doc1", TargetDocVersion: v1.DocVersion}, nil); code != http.StatusOK {
+		t.Fatalf("rollback: status %d", code)
+	}
+	if res := searchAs(t, h, internal, "second revision"); len(res) != 1 || res[0].DocVersion != v1.DocVersion {
+		t.Fatalf("expected v1 after rollback, got %+v", res)
+	}
+
+	if code := doJSON(t, h, "/v1/docs/activate", activateRequest{ProjectID: "proj1", DocID: "doc1", DocVersion: v2.DocVersion}, nil); code != http.StatusOK {
+		t.Fatalf("activate: status %d", code)
+	}
+	if res := searchAs(t, h, internal, "second revision"); len(res) != 1 || res[0].DocVersion != v2.DocVersion {
+		t.Fatalf("expected v2 after activate, got %+v", res)
+	}
+
+	if code := doJSON(t, h, "/v1/docs/delete", deleteRequest{ProjectID: "proj1", DocID: "doc1"}, nil); code != http.StatusOK {
+		t.Fatalf("delete: status %d", code)
+	}
+	if res := searchAs(t, h, internal, "second revision"); len(res) != 0 {
+		t.Fatalf("deleted doc returned: %+v", res)
+	}
+}
+
+func TestE2E_SearchEnforcesACLAndRedaction(t *testing.T) {
+	h, _ := newTestServer(t)
+	content := "Escalations go to oncall@example.com"
+	var ing ingestResponse
+	req := ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: content, ACLAllow: []string{"eng", "customer:acme"}, ACLDeny: []string{"contractors"}}
+	if code := doJSON(t, h, "/v1/docs/ingest", req, &ing); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+	if len(ing.Redactions) != 1 || ing.Redactions[0].Rule != "email" {
+		t.Fatalf("expected email redaction in report, got %+v", ing.Redactions)
+	}
+
+	eng := types.Principal{Type: types.PrincipalInternalUser, ID: "u1", Groups: []string{"eng"}}
+	if res := searchAs(t, h, eng, content); len(res) != 1 || res[0].Text != content {
+		t.Fatalf("internal member should see the unmasked chunk, got %+v", res)
+	}
+	customer := types.Principal{Type: types.PrincipalCustomerUser, ID: "c1", Groups: []string{"customer:acme"}}
+	res := searchAs(t, h, customer, content)
+	if len(res) != 1 || strings.Contains(res[0].Text, "oncall@") {
+		t.Fatalf("customer should see the masked chunk, got %+v", res)
+	}
+	for _, pr := range []types.Principal{
+		{Type: types.PrincipalInternalUser, ID: "u2"},
+		{Type: types.PrincipalInternalUser, ID: "u3", Groups: []string{"eng", "contractors"}},
+		{Type: types.PrincipalCustomerUser, ID: "c2", Groups: []string{"customer:beta"}},
+	} {
+		if res := searchAs(t, h, pr, content); len(res) != 0 {
+			t.Fatalf("%+v must not see the chunk, got %+v", pr, res)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/metadata"
 	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
 	"github.com/HardMakabaka/KB-Gateway/pkg/types"
 )
@@ -17,29 +22,224 @@ func buildBaseFilter(projectScope []string) qdrant.Filter {
 func buildACLFilter(pr types.Principal) qdrant.Filter {
 	// Internal: acl_public OR group intersects
 	// Customer: acl_external_public OR group intersects
+	// Any principal: AND NOT acl_deny intersects groups
 	should := []any{}
-	if pr.Type == types.PrincipalCustomerUser {
+	switch pr.Type {
+	case types.PrincipalCustomerUser:
 		should = append(should, matchBool("acl_external_public", true))
-	} else {
+	case types.PrincipalInternalUser, types.PrincipalService:
 		should = append(should, matchBool("acl_public", true))
+	default:
+		// Unknown principal types see nothing, matching acl.Allowed.
+		return qdrant.Filter{"must": []any{map[string]any{"has_id": []any{}}}}
+	}
+	if len(pr.Groups) == 0 {
+		return qdrant.Filter{"should": should}
+	}
+	should = append(should, matchAny("acl_allow", pr.Groups))
+	return qdrant.Filter{
+		"should":   should,
+		"must_not": []any{matchAny("acl_deny", pr.Groups)},
+	}
+}
+
+// searchFilter is the caller-supplied filter DSL on /v1/search. It can only
+// narrow a search: buildUserFilter emits must/must_not clauses that are AND-ed
+// with the base and ACL filters, and only whitelisted fields may be referenced.
+type searchFilter struct {
+	Eq            map[string]any      `json:"eq,omitempty"`
+	In            map[string][]string `json:"in,omitempty"`
+	CreatedAt     *timeRange          `json:"created_at,omitempty"`
+	UpdatedAt     *timeRange          `json:"updated_at,omitempty"`
+	PathPrefix    string              `json:"path_prefix,omitempty"`
+	DocIDs        []string            `json:"doc_ids,omitempty"`
+	ExcludeDocIDs []string            `json:"exclude_doc_ids,omitempty"`
+}
+
+// timeRange bounds are unix seconds, matching created_at/updated_at in the payload.
+type timeRange struct {
+	GT  *int64 `json:"gt,omitempty"`
+	GTE *int64 `json:"gte,omitempty"`
+	LT  *int64 `json:"lt,omitempty"`
+	LTE *int64 `json:"lte,omitempty"`
+}
+
+// filterableFields are the payload keys callers may use in eq/in clauses, in
+// addition to document metadata under "meta.<key>". ACL, project and lifecycle
+// fields are deliberately absent.
+var filterableFields = map[string]bool{
+	"source":      true,
+	"doc_id":      true,
+	"doc_version": true,
+	"title":       true,
+	"path_or_url": true,
+}
+
+func isFilterable(field string) bool {
+	if filterableFields[field] {
+		return true
+	}
+	key, ok := strings.CutPrefix(field, metadata.PayloadKey+".")
+	return ok && metadata.ValidKey(key)
+}
+
+// eqValue accepts the scalar types Qdrant can match exactly: strings, bools
+// and integers. Gateway fields are all strings, so only metadata may use the others.
+func eqValue(field string, v any) (any, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case bool:
+		if filterableFields[field] {
+			break
+		}
+		return x, nil
+	case float64:
+		if filterableFields[field] || x != math.Trunc(x) {
+			break
+		}
+		return int64(x), nil
+	}
+	return nil, fmt.Errorf("eq: unsupported value for field %q", field)
+}
+
+const maxFilterValues = 256
+
+func buildUserFilter(sf *searchFilter) (qdrant.Filter, error) {
+	if sf == nil {
+		return qdrant.Filter{}, nil
+	}
+	must := []any{}
+	mustNot := []any{}
+
+	for _, k := range metadata.SortedKeys(sf.Eq) {
+		if !isFilterable(k) {
+			return nil, fmt.Errorf("eq: field %q is not filterable", k)
+		}
+		v, err := eqValue(k, sf.Eq[k])
+		if err != nil {
+			return nil, err
+		}
+		must = append(must, matchValue(k, v))
+	}
+	for _, k := range metadata.SortedKeys(sf.In) {
+		if !isFilterable(k) {
+			return nil, fmt.Errorf("in: field %q is not filterable", k)
+		}
+		vals := sf.In[k]
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("in: field %q needs at least one value", k)
+		}
+		if len(vals) > maxFilterValues {
+			return nil, fmt.Errorf("in: field %q has more than %d values", k, maxFilterValues)
+		}
+		must = append(must, matchAny(k, vals))
+	}
+	if sf.CreatedAt != nil {
+		c, err := rangeCondition("created_at", sf.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		must = append(must, c)
+	}
+	if sf.UpdatedAt != nil {
+		c, err := rangeCondition("updated_at", sf.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		must = append(must, c)
+	}
+	if p := normalizePathPrefix(sf.PathPrefix); p != "" {
+		must = append(must, matchValue("path_prefixes", p))
 	}
-	if len(pr.Groups) > 0 {
-		should = append(should, matchAny("acl_allow", pr.Groups))
+	if len(sf.DocIDs) > maxFilterValues || len(sf.ExcludeDocIDs) > maxFilterValues {
+		return nil, fmt.Errorf("doc_ids: more than %d values", maxFilterValues)
 	}
-	return qdrant.Filter{"should": should}
+	if len(sf.DocIDs) > 0 {
+		must = append(must, matchAny("doc_id", sf.DocIDs))
+	}
+	if len(sf.ExcludeDocIDs) > 0 {
+		mustNot = append(mustNot, matchAny("doc_id", sf.ExcludeDocIDs))
+	}
+
+	out := qdrant.Filter{}
+	if len(must) > 0 {
+		out["must"] = must
+	}
+	if len(mustNot) > 0 {
+		out["must_not"] = mustNot
+	}
+	return out, nil
+}
+
+func rangeCondition(field string, tr *timeRange) (map[string]any, error) {
+	r := map[string]any{}
+	if tr.GT != nil {
+		r["gt"] = *tr.GT
+	}
+	if tr.GTE != nil {
+		r["gte"] = *tr.GTE
+	}
+	if tr.LT != nil {
+		r["lt"] = *tr.LT
+	}
+	if tr.LTE != nil {
+		r["lte"] = *tr.LTE
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("%s: empty range", field)
+	}
+	return map[string]any{"key": field, "range": r}, nil
 }
 
-func andFilters(a, b qdrant.Filter) qdrant.Filter {
-	mustA, _ := a["must"].([]any)
-	mustB, _ := b["must"].([]any)
-	shouldA, _ := a["should"].([]any)
-	shouldB, _ := b["should"].([]any)
+// pathPrefixes returns every '/'-delimited prefix of p (including p itself),
+// stored in the payload so path_prefix filters can use an exact keyword match.
+func pathPrefixes(p string) []string {
+	p = normalizePathPrefix(p)
+	if p == "" {
+		return nil
+	}
+	var out []string
+	for i := 1; i < len(p); i++ {
+		if p[i] == '/' && p[i-1] != '/' {
+			out = append(out, p[:i])
+		}
+	}
+	return append(out, p)
+}
+
+func normalizePathPrefix(p string) string {
+	return strings.TrimRight(strings.TrimSpace(p), "/")
+}
+
+// andFilters combines filters so that every input must hold. must and
+// must_not lists are concatenated; should lists are kept as separate nested
+// clauses so one filter's alternatives can never satisfy another's.
+func andFilters(filters ...qdrant.Filter) qdrant.Filter {
+	var must, mustNot, shoulds []any
+	for _, f := range filters {
+		m, _ := f["must"].([]any)
+		mn, _ := f["must_not"].([]any)
+		sh, _ := f["should"].([]any)
+		must = append(must, m...)
+		mustNot = append(mustNot, mn...)
+		if len(sh) > 0 {
+			shoulds = append(shoulds, sh)
+		}
+	}
 	out := qdrant.Filter{}
-	if len(mustA)+len(mustB) > 0 {
-		out["must"] = append(mustA, mustB...)
+	if len(shoulds) == 1 {
+		out["should"] = shoulds[0]
+	} else {
+		for _, sh := range shoulds {
+			must = append(must, map[string]any{"should": sh})
+		}
+	}
+	if len(must) > 0 {
+		out["must"] = must
 	}
-	if len(shouldA)+len(shouldB) > 0 {
-		out["should"] = append(shouldA, shouldB...)
+	if len(mustNot) > 0 {
+		out["must_not"] = mustNot
 	}
 	return out
 }
@@ -53,7 +253,7 @@ func matchAny(field string, values []string) map[string]any {
 	}
 }
 
-func matchBool(field string, value bool) map[string]any {
+func matchValue(field string, value any) map[string]any {
 	return map[string]any{
 		"key": field,
 		"match": map[string]any{
@@ -61,3 +261,7 @@ func matchBool(field string, value bool) map[string]any {
 		},
 	}
 }
+
+func matchBool(field string, value bool) map[string]any {
+	return matchValue(field, value)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
 	"github.com/HardMakabaka/KB-Gateway/pkg/types"
 )
 
@@ -19,6 +20,127 @@ func TestBuildACLFilter_CustomerDoesNotUseInternalPublic(t *testing.T) {
 	}
 }
 
+func TestBuildACLFilter_DenyIsMustNot(t *testing.T) {
+	for _, typ := range []types.PrincipalType{types.PrincipalInternalUser, types.PrincipalCustomerUser, types.PrincipalService} {
+		f := buildACLFilter(types.Principal{Type: typ, Groups: []string{"contractors"}})
+		mustNot, _ := f["must_not"].([]any)
+		if len(mustNot) != 1 {
+			t.Fatalf("%s: expected one must_not clause, got %v", typ, f)
+		}
+		b, _ := json.Marshal(mustNot[0])
+		if !contains(string(b), "acl_deny") || !contains(string(b), "contractors") {
+			t.Fatalf("%s: expected acl_deny must_not on principal groups, got %s", typ, b)
+		}
+	}
+	if f := buildACLFilter(types.Principal{Type: types.PrincipalInternalUser}); f["must_not"] != nil {
+		t.Fatalf("principal without groups cannot be denied, got %v", f)
+	}
+}
+
+func TestBuildUserFilter_RejectsProtectedFields(t *testing.T) {
+	for _, field := range []string{"acl_public", "acl_allow", "acl_deny", "project_id", "is_active", "deleted"} {
+		if _, err := buildUserFilter(&searchFilter{Eq: map[string]any{field: "x"}}); err == nil {
+			t.Fatalf("expected eq on %s to be rejected", field)
+		}
+		if _, err := buildUserFilter(&searchFilter{In: map[string][]string{field: {"x"}}}); err == nil {
+			t.Fatalf("expected in on %s to be rejected", field)
+		}
+	}
+}
+
+func TestBuildUserFilter_Clauses(t *testing.T) {
+	from := int64(100)
+	f, err := buildUserFilter(&searchFilter{
+		Eq:            map[string]any{"source": "git"},
+		CreatedAt:     &timeRange{GTE: &from},
+		PathPrefix:    "docs/api/",
+		ExcludeDocIDs: []string{"docB"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	must, _ := f["must"].([]any)
+	if len(must) != 3 {
+		t.Fatalf("expected 3 must clauses, got %d: %v", len(must), f)
+	}
+	mustNot, _ := f["must_not"].([]any)
+	if len(mustNot) != 1 {
+		t.Fatalf("expected doc exclusion in must_not: %v", f)
+	}
+	b, _ := json.Marshal(f)
+	if !contains(string(b), `"path_prefixes","match":{"value":"docs/api"}`) {
+		t.Fatalf("expected normalized path prefix match: %s", b)
+	}
+	if _, err := buildUserFilter(&searchFilter{UpdatedAt: &timeRange{}}); err == nil {
+		t.Fatalf("expected empty range to be rejected")
+	}
+}
+
+func TestBuildUserFilter_Metadata(t *testing.T) {
+	f, err := buildUserFilter(&searchFilter{
+		Eq: map[string]any{"meta.language": "en", "meta.priority": float64(2)},
+		In: map[string][]string{"meta.tags": {"billing", "sso"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := json.Marshal(f)
+	for _, want := range []string{`"meta.language"`, `"meta.tags"`, `"value":2}`} {
+		if !contains(string(b), want) {
+			t.Fatalf("expected %s in %s", want, b)
+		}
+	}
+	bad := []map[string]any{
+		{"meta.Bad-Key": "x"},
+		{"meta.priority": 1.5},
+		{"source": true},
+		{"meta.tags": []any{"x"}},
+	}
+	for _, eq := range bad {
+		if _, err := buildUserFilter(&searchFilter{Eq: eq}); err == nil {
+			t.Fatalf("expected %v to be rejected", eq)
+		}
+	}
+}
+
+func TestAndFilters_KeepsShouldGroupsSeparate(t *testing.T) {
+	acl := buildACLFilter(types.Principal{Type: types.PrincipalInternalUser, Groups: []string{"eng"}})
+	other := qdrantShould(matchBool("acl_external_public", true))
+	f := andFilters(buildBaseFilter([]string{"p1"}), acl, other)
+	if _, ok := f["should"]; ok {
+		t.Fatalf("multiple should groups must not be merged at top level: %v", f)
+	}
+	must, _ := f["must"].([]any)
+	if len(must) != 5 {
+		t.Fatalf("expected 3 base clauses plus 2 nested should groups, got %d", len(must))
+	}
+
+	single := andFilters(buildBaseFilter([]string{"p1"}), acl, qdrantMust())
+	if _, ok := single["should"]; !ok {
+		t.Fatalf("single should group should stay at top level: %v", single)
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	got := pathPrefixes("docs/api/search.md")
+	want := []string{"docs", "docs/api", "docs/api/search.md"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v want %v", got, want)
+		}
+	}
+	if pathPrefixes("") != nil {
+		t.Fatalf("expected no prefixes for empty path")
+	}
+}
+
+func qdrantShould(conds ...any) qdrant.Filter { return qdrant.Filter{"should": conds} }
+
+func qdrantMust(conds ...any) qdrant.Filter { return qdrant.Filter{"must": conds} }
+
 func contains(s, sub string) bool { return len(s) >= 0 && (stringIndex(s, sub) >= 0) }
 
 func stringIndex(s, sub string) int {
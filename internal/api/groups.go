@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/groups"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+type explainGroupsRequest struct {
+	Principal types.Principal `json:"principal"`
+}
+
+type explainGroupsResponse struct {
+	Principal       types.Principal    `json:"principal"`
+	EffectiveGroups []string           `json:"effective_groups"`
+	Expansions      []groups.Expansion `json:"expansions"`
+}
+
+// handleExplainGroups reports the effective groups of the caller, or of the
+// body principal for callers allowed to impersonate.
+func (s *Server) handleExplainGroups(w http.ResponseWriter, r *http.Request) {
+	var req explainGroupsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+			return
+		}
+	}
+	pr, apiErr := s.requestPrincipal(r, req.Principal)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	resp := explainGroupsResponse{Principal: pr, EffectiveGroups: []string{}, Expansions: []groups.Expansion{}}
+	if s.groups != nil {
+		resp.Expansions = s.groups.Explain(pr)
+	} else {
+		for _, g := range pr.Groups {
+			resp.Expansions = append(resp.Expansions, groups.Expansion{Group: g, Source: groups.SourceDirect})
+		}
+	}
+	for _, e := range resp.Expansions {
+		resp.EffectiveGroups = append(resp.EffectiveGroups, e.Group)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -11,25 +11,60 @@ import (
 	"time"
 
 	"github.com/HardMakabaka/KB-Gateway/internal/chunk"
+	"github.com/HardMakabaka/KB-Gateway/internal/metadata"
 	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/internal/redact"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
 	"github.com/HardMakabaka/KB-Gateway/pkg/types"
 	"github.com/google/uuid"
 )
 
 type ingestRequest struct {
-	ProjectID string   `json:"project_id"`
-	DocID     string   `json:"doc_id"`
-	Title     string   `json:"title"`
-	Source    string   `json:"source"`
-	PathOrURL string   `json:"path_or_url"`
-	Content   string   `json:"content"`
-	ACLPublic bool     `json:"acl_public"`
-	ACLAllow  []string `json:"acl_allow"`
+	ProjectID string         `json:"project_id"`
+	DocID     string         `json:"doc_id"`
+	Title     string         `json:"title"`
+	Source    string         `json:"source"`
+	PathOrURL string         `json:"path_or_url"`
+	Content   string         `json:"content"`
+	ACLPublic bool           `json:"acl_public"`
+	ACLAllow  []string       `json:"acl_allow"`
+	ACLDeny   []string       `json:"acl_deny"`
+	Metadata  map[string]any `json:"metadata"`
 }
 
 type ingestResponse struct {
-	DocVersion    string `json:"doc_version"`
-	ChunksWritten int    `json:"chunks_written"`
+	DocVersion    string            `json:"doc_version"`
+	ChunksWritten int               `json:"chunks_written"`
+	Redactions    []redactionReport `json:"redactions"`
+}
+
+// redactionReport summarises the spans one rule tagged at ingest.
+type redactionReport struct {
+	Rule     string `json:"rule"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	ChunkIDs []int  `json:"chunk_ids"`
+}
+
+func buildRedactionReport(red *redact.Redactor, spans [][]redact.Span) []redactionReport {
+	byRule := map[string]*redactionReport{}
+	var out []redactionReport
+	for i, chunkSpans := range spans {
+		for _, sp := range chunkSpans {
+			rep, ok := byRule[sp.Rule]
+			if !ok {
+				out = append(out, redactionReport{Rule: sp.Rule, Category: red.Category(sp.Rule)})
+				rep = &out[len(out)-1]
+				byRule[sp.Rule] = rep
+			}
+			rep.Count++
+			if n := len(rep.ChunkIDs); n == 0 || rep.ChunkIDs[n-1] != i {
+				rep.ChunkIDs = append(rep.ChunkIDs, i)
+			}
+		}
+	}
+	return out
 }
 
 func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +77,22 @@ func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
 		return
 	}
+	for _, groups := range [][]string{req.ACLAllow, req.ACLDeny} {
+		if err := validateGroups(groups); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_acl", "detail": err.Error()})
+			return
+		}
+	}
+
+	if apiErr := s.authorize(r, req.ProjectID, rbac.RoleEditor); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	meta, err := s.metadata.Validate(req.ProjectID, req.Metadata)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_metadata", "detail": err.Error()})
+		return
+	}
 
 	unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
 	defer unlock()
@@ -56,6 +107,7 @@ func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 	if len(chunks) == 0 {
 		chunks = []string{strings.TrimSpace(req.Content)}
 	}
+	redactions := s.redactor.DetectChunks(req.Content, chunks)
 	vecs, err := s.embedder.Embed(r.Context(), chunks)
 	if err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "embed_failed"})
@@ -74,14 +126,18 @@ func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 			Source:            req.Source,
 			Title:             req.Title,
 			PathOrURL:         req.PathOrURL,
+			PathPrefixes:      pathPrefixes(req.PathOrURL),
 			Text:              c,
 			ContentHash:       hashString(c),
 			ACLPublic:         req.ACLPublic,
 			ACLExternalPublic: false,
 			ACLAllow:          req.ACLAllow,
+			ACLDeny:           req.ACLDeny,
+			Redactions:        redactions[i],
 			CreatedAt:         docVersionTS,
 			UpdatedAt:         docVersionTS,
 			Deleted:           false,
+			Meta:              meta,
 		}
 		p := map[string]any{}
 		b, _ := json.Marshal(payload)
@@ -94,17 +150,22 @@ func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	if err := s.qdrant.Upsert(r.Context(), s.cfg.Qdrant.Collection, points); err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "qdrant_upsert_failed", "detail": err.Error()})
+	batching := store.BatchOptions{Size: s.cfg.Store.UpsertBatchSize, Parallelism: s.cfg.Store.UpsertParallelism}
+	if err := store.UpsertWithRetry(r.Context(), s.store, s.collection, points, batching, s.cfg.Store.UpsertRetries); err != nil {
+		storeError("qdrant_upsert_failed", err).write(w)
 		return
 	}
 
 	if err := s.activateLocked(r.Context(), req.ProjectID, req.DocID, docVersion); err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "activate_failed", "detail": err.Error()})
+		storeError("activate_failed", err).write(w)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, ingestResponse{DocVersion: docVersion, ChunksWritten: len(chunks)})
+	report := buildRedactionReport(s.redactor, redactions)
+	if report == nil {
+		report = []redactionReport{}
+	}
+	writeJSON(w, http.StatusOK, ingestResponse{DocVersion: docVersion, ChunksWritten: len(chunks), Redactions: report})
 }
 
 type activateRequest struct {
@@ -123,33 +184,43 @@ func (s *Server) handleActivate(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
 		return
 	}
+	if apiErr := s.authorize(r, req.ProjectID, rbac.RoleEditor); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
 
 	unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
 	defer unlock()
 
 	if err := s.activateLocked(r.Context(), req.ProjectID, req.DocID, req.DocVersion); err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "activate_failed", "detail": err.Error()})
+		storeError("activate_failed", err).write(w)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
 func (s *Server) activateLocked(ctx context.Context, projectID, docID, docVersion string) error {
+	return s.activateIn(ctx, s.collection, projectID, docID, docVersion)
+}
+
+// activateIn is activateLocked for any collection.
+func (s *Server) activateIn(ctx context.Context, collection, projectID, docID, docVersion string) error {
 	fDeactivate := qdrant.Filter{"must": []any{
 		map[string]any{"key": "project_id", "match": map[string]any{"value": projectID}},
 		map[string]any{"key": "doc_id", "match": map[string]any{"value": docID}},
 		map[string]any{"key": "is_active", "match": map[string]any{"value": true}},
 	}}
-	if err := s.qdrant.SetPayload(ctx, s.cfg.Qdrant.Collection, map[string]any{"is_active": false}, fDeactivate); err != nil {
-		return err
-	}
-
 	fActivate := qdrant.Filter{"must": []any{
 		map[string]any{"key": "project_id", "match": map[string]any{"value": projectID}},
 		map[string]any{"key": "doc_id", "match": map[string]any{"value": docID}},
 		map[string]any{"key": "doc_version", "match": map[string]any{"value": docVersion}},
 	}}
-	return s.qdrant.SetPayload(ctx, s.cfg.Qdrant.Collection, map[string]any{"is_active": true, "updated_at": time.Now().UTC().Unix()}, fActivate)
+	return store.InTx(ctx, s.store, func(st store.VectorStore) error {
+		if err := st.SetPayload(ctx, collection, map[string]any{"is_active": false}, fDeactivate); err != nil {
+			return err
+		}
+		return st.SetPayload(ctx, collection, map[string]any{"is_active": true, "updated_at": time.Now().UTC().Unix()}, fActivate)
+	})
 }
 
 type searchRequest struct {
@@ -157,21 +228,36 @@ type searchRequest struct {
 	ProjectScope []string        `json:"project_scope"`
 	Principal    types.Principal `json:"principal"`
 	TopK         int             `json:"top_k"`
+	Filter       *searchFilter   `json:"filter"`
+
+	ScoreThreshold *float64 `json:"score_threshold"`
+	Offset         int      `json:"offset"`
+	// Cursor is a next_cursor from a previous page; Query may then be omitted.
+	Cursor string `json:"cursor"`
 }
 
 type searchResult struct {
-	Text       string  `json:"text"`
-	Score      float64 `json:"score"`
-	ProjectID  string  `json:"project_id"`
-	DocID      string  `json:"doc_id"`
-	DocVersion string  `json:"doc_version"`
-	ChunkID    int     `json:"chunk_id"`
-	Title      string  `json:"title"`
-	PathOrURL  string  `json:"path_or_url"`
+	Text       string         `json:"text"`
+	Score      float64        `json:"score"`
+	ProjectID  string         `json:"project_id"`
+	DocID      string         `json:"doc_id"`
+	DocVersion string         `json:"doc_version"`
+	ChunkID    int            `json:"chunk_id"`
+	Title      string         `json:"title"`
+	PathOrURL  string         `json:"path_or_url"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+
+	// rawText is Text before masking; redactions are its tagged spans.
+	rawText    string
+	redactions []redact.Span
 }
 
 type searchResponse struct {
 	Results []searchResult `json:"results"`
+	// ProjectScope is the scope actually searched, after dropping projects
+	// the principal may not read.
+	ProjectScope []string `json:"project_scope"`
+	NextCursor   string   `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
@@ -180,33 +266,89 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
 		return
 	}
-	if strings.TrimSpace(req.Query) == "" || len(req.ProjectScope) == 0 {
+	if (strings.TrimSpace(req.Query) == "" && req.Cursor == "") || len(req.ProjectScope) == 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
 		return
 	}
+	pr, scope, apiErr := s.searchPrincipal(r, req.Principal, req.ProjectScope)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	req.Principal = pr
+	req.ProjectScope = scope
+	resp, apiErr := s.search(r.Context(), req)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// search runs the ACL-filtered vector search shared by /v1/search and /v1/answer.
+func (s *Server) search(ctx context.Context, req searchRequest) (searchResponse, *apiError) {
 	limit := req.TopK
 	if limit <= 0 {
 		limit = 10
 	}
-
-	vecs, err := s.embedder.Embed(r.Context(), []string{req.Query})
+	userFilter, err := buildUserFilter(req.Filter)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "embed_failed"})
-		return
+		return searchResponse{}, &apiError{http.StatusBadRequest, "invalid_filter", err.Error()}
+	}
+
+	fingerprint := searchFingerprint(req, limit)
+	offset := req.Offset
+	var vec []float32
+	var vecHash string
+	if req.Cursor != "" {
+		c, err := decodeCursor(req.Cursor)
+		if err != nil || c.Fingerprint != fingerprint {
+			return searchResponse{}, &apiError{Status: http.StatusBadRequest, Code: "invalid_cursor"}
+		}
+		offset = c.Offset
+		vecHash = c.EmbeddingHash
+		vec, _ = s.queryVectors.Get(vecHash)
+	}
+	if offset < 0 || offset > maxSearchOffset {
+		return searchResponse{}, &apiError{Status: http.StatusBadRequest, Code: "invalid_offset"}
+	}
+
+	if vec == nil {
+		// Cursor vectors are cached in memory only; without the query we cannot rebuild it.
+		if strings.TrimSpace(req.Query) == "" {
+			return searchResponse{}, &apiError{Status: http.StatusGone, Code: "cursor_expired"}
+		}
+		vecs, err := s.embedder.Embed(ctx, []string{req.Query})
+		if err != nil {
+			return searchResponse{}, &apiError{Status: http.StatusBadGateway, Code: "embed_failed"}
+		}
+		vec = vecs[0]
+		h := hashVector(vec)
+		if vecHash != "" && h != vecHash {
+			return searchResponse{}, &apiError{Status: http.StatusBadRequest, Code: "invalid_cursor"}
+		}
+		vecHash = h
+		s.queryVectors.Put(vecHash, vec)
 	}
 
-	f := andFilters(buildBaseFilter(req.ProjectScope), buildACLFilter(req.Principal))
-	res, err := s.qdrant.Search(r.Context(), s.cfg.Qdrant.Collection, vecs[0], f, limit)
+	f := andFilters(buildBaseFilter(req.ProjectScope), buildACLFilter(req.Principal), userFilter)
+	opts := qdrant.SearchOptions{Offset: offset, ScoreThreshold: req.ScoreThreshold}
+	res, err := s.store.Search(ctx, s.collection, vec, f, limit, opts)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "qdrant_search_failed", "detail": err.Error()})
-		return
+		return searchResponse{}, storeError("qdrant_search_failed", err)
 	}
 
 	out := make([]searchResult, 0, len(res))
 	for _, it := range res {
 		p := it.Payload
+		if reason := hitViolation(req.Principal, req.ProjectScope, p); reason != "" {
+			logSecurityEvent(ctx, req.Principal, it.ID, p, reason)
+			continue
+		}
+		text := toString(p["text"])
+		spans := redact.SpansFromPayload(p[redact.PayloadKey])
 		out = append(out, searchResult{
-			Text:       toString(p["text"]),
+			Text:       s.redactor.Mask(text, spans, req.Principal.Type),
 			Score:      it.Score,
 			ProjectID:  toString(p["project_id"]),
 			DocID:      toString(p["doc_id"]),
@@ -214,9 +356,16 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 			ChunkID:    toInt(p["chunk_id"]),
 			Title:      toString(p["title"]),
 			PathOrURL:  toString(p["path_or_url"]),
+			Metadata:   toMap(p[metadata.PayloadKey]),
+			rawText:    text,
+			redactions: spans,
 		})
 	}
-	writeJSON(w, http.StatusOK, searchResponse{Results: out})
+	resp := searchResponse{Results: out, ProjectScope: req.ProjectScope}
+	if len(res) == limit && offset+limit <= maxSearchOffset {
+		resp.NextCursor = encodeCursor(searchCursor{EmbeddingHash: vecHash, Offset: offset + limit, Fingerprint: fingerprint})
+	}
+	return resp, nil
 }
 
 func toString(v any) string {
@@ -238,6 +387,11 @@ func toInt(v any) int {
 	}
 }
 
+func toMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
 func hashString(s string) string {
 	h := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(h[:])
@@ -259,6 +413,15 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
 		return
 	}
+	// Hard deletes destroy every version irrecoverably, so they need admin.
+	minRole := rbac.RolePublisher
+	if req.Hard {
+		minRole = rbac.RoleAdmin
+	}
+	if apiErr := s.authorize(r, req.ProjectID, minRole); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
 
 	unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
 	defer unlock()
@@ -269,8 +432,8 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}}
 
 	if req.Hard {
-		if err := s.qdrant.DeleteByFilter(r.Context(), s.cfg.Qdrant.Collection, f); err != nil {
-			writeJSON(w, http.StatusBadGateway, map[string]any{"error": "qdrant_delete_failed", "detail": err.Error()})
+		if err := s.store.DeleteByFilter(r.Context(), s.collection, f); err != nil {
+			storeError("qdrant_delete_failed", err).write(w)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
@@ -279,8 +442,8 @@ func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
 
 	// Soft delete: mark deleted and deactivate all versions.
 	payload := map[string]any{"deleted": true, "is_active": false, "updated_at": time.Now().UTC().Unix()}
-	if err := s.qdrant.SetPayload(r.Context(), s.cfg.Qdrant.Collection, payload, f); err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "qdrant_delete_failed", "detail": err.Error()})
+	if err := s.store.SetPayload(r.Context(), s.collection, payload, f); err != nil {
+		storeError("qdrant_delete_failed", err).write(w)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
@@ -302,12 +465,16 @@ func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
 		return
 	}
+	if apiErr := s.authorize(r, req.ProjectID, rbac.RolePublisher); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
 
 	unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
 	defer unlock()
 
 	if err := s.activateLocked(r.Context(), req.ProjectID, req.DocID, req.TargetDocVersion); err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]any{"error": "rollback_failed", "detail": err.Error()})
+		storeError("rollback_failed", err).write(w)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
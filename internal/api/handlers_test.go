@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/redact"
+)
+
+func TestIngest_ValidatesACLGroups(t *testing.T) {
+	h, _ := newTestServer(t)
+	for name, req := range map[string]ingestRequest{
+		"allow": {ProjectID: "proj1", DocID: "doc1", Content: "x", ACLAllow: []string{"eng team"}},
+		"deny":  {ProjectID: "proj1", DocID: "doc1", Content: "x", ACLAllow: []string{"eng"}, ACLDeny: []string{""}},
+	} {
+		if code := doJSON(t, h, "/v1/docs/ingest", req, nil); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 for a malformed group, got %d", name, code)
+		}
+	}
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "x", ACLAllow: []string{"eng"}}, nil); code != http.StatusOK {
+		t.Fatalf("expected valid groups to be accepted, got %d", code)
+	}
+}
+
+func TestBuildRedactionReport(t *testing.T) {
+	red, err := redact.New(redact.DefaultRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := red.DetectChunks("a@example.com\n\nb@example.com and c@example.com", []string{"a@example.com", "b@example.com and c@example.com"})
+	report := buildRedactionReport(red, spans)
+	if len(report) != 1 {
+		t.Fatalf("expected one rule in report, got %+v", report)
+	}
+	got := report[0]
+	if got.Rule != "email" || got.Category != "pii" || got.Count != 3 || len(got.ChunkIDs) != 2 {
+		t.Fatalf("unexpected report %+v", got)
+	}
+}
@@ -5,9 +5,12 @@ import "sync"
 type KeyedMutex struct {
 	mu    sync.Mutex
 	locks map[string]*sync.Mutex
+	// all is held shared by every Lock and exclusively by LockAll.
+	all sync.RWMutex
 }
 
 func (k *KeyedMutex) Lock(key string) func() {
+	k.all.RLock()
 	k.mu.Lock()
 	if k.locks == nil {
 		k.locks = make(map[string]*sync.Mutex)
@@ -20,5 +23,13 @@ func (k *KeyedMutex) Lock(key string) func() {
 	k.mu.Unlock()
 
 	m.Lock()
-	return func() { m.Unlock() }
+	return func() { m.Unlock(); k.all.RUnlock() }
+}
+
+// LockAll waits for every held key lock to be released and blocks new ones
+// until the returned func is called. It must not be called while holding a
+// key lock.
+func (k *KeyedMutex) LockAll() func() {
+	k.all.Lock()
+	return k.all.Unlock
 }
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/embed"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+)
+
+// A migration job builds a new collection from the one behind the alias
+// while the gateway keeps serving from it:
+//
+//  1. create the target collection and its payload indexes;
+//  2. mirror every write to the alias into the target;
+//  3. backfill each document under its doc lock;
+//  4. with all doc locks held, repoint the alias and swap the embedder.
+//
+// Points are re-embedded with the job's embedder, or copied with their
+// vectors when it has none. Re-embedding migrations flip the alias as soon
+// as the backfill is done; collection builds wait in the ready state, still
+// mirroring, until an admin switches to them. The source collection is kept
+// for rollback. Progress lives in memory: a restart abandons the target and
+// leaves the alias where it was.
+
+const (
+	migrationRunning   = "running"
+	migrationReady     = "ready"
+	migrationCompleted = "completed"
+	migrationAborted   = "aborted"
+	migrationFailed    = "failed"
+)
+
+type migrationRequest struct {
+	Model string `json:"model"`
+	Dim   int    `json:"dim"`
+	// Collection names the target; defaults to <collection>_<timestamp>.
+	Collection string `json:"collection"`
+}
+
+type migrationStatus struct {
+	ID     string `json:"id"`
+	State  string `json:"state"`
+	Alias  string `json:"alias"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Mode is "reembed" or "copy".
+	Mode           string     `json:"mode"`
+	Model          string     `json:"model,omitempty"`
+	Dim            int        `json:"dim"`
+	TotalPoints    int        `json:"total_points"`
+	MigratedPoints int        `json:"migrated_points"`
+	MigratedDocs   int        `json:"migrated_docs"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	// Error is a stable code and ErrorDetail a short reason for a failed
+	// migration; the cause itself is only logged.
+	Error       string `json:"error,omitempty"`
+	ErrorDetail string `json:"error_detail,omitempty"`
+}
+
+type migrationJob struct {
+	mu      sync.Mutex
+	status  migrationStatus
+	failure error
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// embedder re-embeds points; nil copies their vectors.
+	embedder   embed.Embedder
+	autoSwitch bool
+	// flip asks a ready job to switch the alias and reports the result.
+	flip chan chan error
+}
+
+func (j *migrationJob) snapshot() migrationStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *migrationJob) update(fn func(*migrationStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// fail records the first failure and stops the job.
+func (j *migrationJob) fail(err error) {
+	j.mu.Lock()
+	if j.failure == nil {
+		j.failure = err
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+func (j *migrationJob) err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.failure
+}
+
+// migrationState holds the running or most recent migration.
+type migrationState struct {
+	mu  sync.Mutex
+	job *migrationJob
+}
+
+func (m *migrationState) current() *migrationJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.job
+}
+
+// active returns the job that is running or ready, if any.
+func (m *migrationState) active() *migrationJob {
+	if j := m.current(); j != nil {
+		if st := j.snapshot().State; st == migrationRunning || st == migrationReady {
+			return j
+		}
+	}
+	return nil
+}
+
+// authorizeAdmin allows API keys with the admin scope for every project and
+// principals holding the admin role on "*".
+func (s *Server) authorizeAdmin(r *http.Request) *apiError {
+	if k, ok := apikey.FromContext(r.Context()); ok && k.ProjectID != apikey.AllProjects {
+		return &apiError{Status: http.StatusForbidden, Code: "project_not_allowed"}
+	}
+	return s.authorize(r, rbac.AllProjects, rbac.RoleAdmin)
+}
+
+func (s *Server) handleStartMigration(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	var req migrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	if req.Model == "" || req.Dim <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields", "detail": "model and dim are required"})
+		return
+	}
+	spec := migrationStatus{Mode: "reembed", Model: req.Model, Dim: req.Dim, Target: req.Collection}
+	job, apiErr := s.startJob(r, spec, s.newEmbedder(embedConfigFor(s.cfg.Embed, req)), true)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+// startJob validates spec against the alias and the running job, then starts
+// building spec.Target (or a timestamped name) in the background.
+func (s *Server) startJob(r *http.Request, spec migrationStatus, emb embed.Embedder, autoSwitch bool) (*migrationJob, *apiError) {
+	alias := s.cfg.Qdrant.Alias
+	as, ok := s.store.aliases()
+	if alias == "" || !ok {
+		return nil, &apiError{Status: http.StatusConflict, Code: "alias_required", Detail: "needs KBG_QDRANT_ALIAS and a backend with aliases"}
+	}
+	source, err := as.ResolveAlias(r.Context(), alias)
+	if err != nil {
+		return nil, storeError("resolve_alias_failed", err)
+	}
+	if source == "" {
+		return nil, &apiError{Status: http.StatusServiceUnavailable, Code: "alias_missing"}
+	}
+	now := time.Now().UTC()
+	if spec.Target == "" {
+		spec.Target = fmt.Sprintf("%s_%s", s.cfg.Qdrant.Collection, now.Format("20060102150405"))
+	}
+	if spec.Target == source || spec.Target == alias {
+		return nil, &apiError{Status: http.StatusConflict, Code: "target_in_use"}
+	}
+	// A failed job drops its target, so it must not be an existing collection.
+	if _, err := s.store.CollectionInfo(r.Context(), spec.Target); err == nil {
+		return nil, &apiError{Status: http.StatusConflict, Code: "target_exists"}
+	} else if !errors.Is(err, qdrant.ErrNotFound) {
+		return nil, storeError("collection_info_failed", err)
+	}
+
+	s.migration.mu.Lock()
+	defer s.migration.mu.Unlock()
+	if j := s.migration.job; j != nil {
+		if st := j.snapshot().State; st == migrationRunning || st == migrationReady {
+			return nil, &apiError{Status: http.StatusConflict, Code: "migration_running"}
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	spec.ID, spec.State, spec.StartedAt = now.Format("20060102T150405Z"), migrationRunning, now
+	spec.Alias, spec.Source = alias, source
+	job := &migrationJob{
+		status:     spec,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		embedder:   emb,
+		autoSwitch: autoSwitch,
+		flip:       make(chan chan error),
+	}
+	s.migration.job = job
+	log.Printf("migration %s: building %s from %s (%s) by %s", spec.ID, spec.Target, alias, source, spec.Mode)
+	go s.runMigration(ctx, job, as)
+	return job, nil
+}
+
+func (s *Server) handleGetMigration(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	job := s.migration.current()
+	if job == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "no_migration"})
+		return
+	}
+	writeJSON(w, http.StatusOK, job.snapshot())
+}
+
+func (s *Server) handleAbortMigration(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	job := s.migration.active()
+	if job == nil {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "migration_not_running"})
+		return
+	}
+	job.cancel()
+	<-job.done
+	writeJSON(w, http.StatusOK, job.snapshot())
+}
+
+func embedConfigFor(cfg config.EmbedConfig, req migrationRequest) config.EmbedConfig {
+	cfg.Model, cfg.Dim = req.Model, req.Dim
+	return cfg
+}
+
+func (s *Server) runMigration(ctx context.Context, job *migrationJob, as store.AliasStore) {
+	defer close(job.done)
+	st := job.snapshot()
+
+	err := s.build(ctx, job, st)
+	if err == nil && job.autoSwitch {
+		err = s.flip(ctx, job, as, st)
+	} else if err == nil {
+		err = s.awaitSwitch(ctx, job, as, st)
+	}
+	s.store.stopMirror()
+	if err == nil {
+		job.update(func(m *migrationStatus) {
+			now := time.Now().UTC()
+			m.State, m.FinishedAt = migrationCompleted, &now
+		})
+		log.Printf("migration %s: %s now points at %s", st.ID, st.Alias, st.Target)
+		return
+	}
+
+	state := migrationAborted
+	if ferr := job.err(); ferr != nil {
+		err, state = ferr, migrationFailed
+	} else if ctx.Err() == nil {
+		state = migrationFailed
+	}
+	log.Printf("migration %s %s: %v", st.ID, state, err)
+	cleanup, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if derr := as.DeleteCollection(cleanup, st.Target); derr != nil {
+		log.Printf("migration %s: drop %s: %v", st.ID, st.Target, derr)
+	}
+	job.update(func(m *migrationStatus) {
+		now := time.Now().UTC()
+		m.State, m.FinishedAt = state, &now
+		if state == migrationFailed {
+			m.Error, m.ErrorDetail = migrationError(err)
+		}
+	})
+}
+
+// errMigrationEmbed marks failures of the embedder during a migration.
+var errMigrationEmbed = errors.New("embed failed")
+
+// migrationError maps a migration failure to the code and reason reported
+// by the status endpoint.
+func migrationError(err error) (code, detail string) {
+	var mismatch *schemaMismatchError
+	switch {
+	case errors.As(err, &mismatch):
+		return "schema_mismatch", mismatch.Error()
+	case errors.Is(err, errMigrationEmbed):
+		return "embed_failed", ""
+	}
+	apiErr := classifyStoreError("migration_failed", err)
+	return apiErr.Code, apiErr.Detail
+}
+
+// build creates st.Target, starts mirroring into it and backfills it.
+func (s *Server) build(ctx context.Context, job *migrationJob, st migrationStatus) error {
+	if job.embedder != nil && job.embedder.Dim() != st.Dim {
+		return fmt.Errorf("%w: embedder for %s has dim %d, requested %d", errMigrationEmbed, st.Model, job.embedder.Dim(), st.Dim)
+	}
+	if err := s.store.EnsureCollection(ctx, st.Target, st.Dim); err != nil {
+		return fmt.Errorf("create %s: %w", st.Target, err)
+	}
+	info, err := s.store.CollectionInfo(ctx, st.Target)
+	if err != nil {
+		return fmt.Errorf("collection info: %w", err)
+	}
+	if info.VectorSize != st.Dim {
+		return &schemaMismatchError{collection: st.Target, wantSize: st.Dim, gotSize: info.VectorSize, gotDist: info.Distance}
+	}
+	if err := s.createIndexes(ctx, st.Target); err != nil {
+		return err
+	}
+	total, err := s.store.Count(ctx, st.Source, nil)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+	job.update(func(m *migrationStatus) { m.TotalPoints = total })
+
+	s.store.startMirror(&mirror{from: st.Alias, to: st.Target, embedder: job.embedder, fail: job.fail})
+	return s.backfill(ctx, job, st)
+}
+
+// flip points the alias at the target once no doc lock is held. Writes
+// resume against the target, embedded by the job's embedder.
+func (s *Server) flip(ctx context.Context, job *migrationJob, as store.AliasStore, st migrationStatus) error {
+	unlock := s.docLocks.LockAll()
+	defer unlock()
+	if err := job.err(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := as.SwitchAlias(ctx, st.Alias, st.Target); err != nil {
+		return fmt.Errorf("switch alias: %w", err)
+	}
+	s.store.stopMirror()
+	if job.embedder != nil {
+		s.embedder.Swap(job.embedder)
+		s.queryVectors.Reset()
+	}
+	return nil
+}
+
+// awaitSwitch keeps a built target in sync until it is switched to or the
+// job is aborted.
+func (s *Server) awaitSwitch(ctx context.Context, job *migrationJob, as store.AliasStore, st migrationStatus) error {
+	job.update(func(m *migrationStatus) { m.State = migrationReady })
+	log.Printf("migration %s: %s is ready", st.ID, st.Target)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case reply := <-job.flip:
+			err := s.flip(ctx, job, as, st)
+			reply <- err
+			if err == nil {
+				return nil
+			}
+		}
+	}
+}
+
+// backfill copies every document of the source into the target. Each
+// document is re-read under its doc lock, so it cannot interleave with a
+// mirrored write to the same document.
+func (s *Server) backfill(ctx context.Context, job *migrationJob, st migrationStatus) error {
+	done := map[[2]string]bool{}
+	var offset any
+	for {
+		page, err := s.store.Scroll(ctx, st.Source, nil, 256, offset, false)
+		if err != nil {
+			return fmt.Errorf("scroll %s: %w", st.Source, err)
+		}
+		for _, p := range page.Points {
+			projectID, _ := p.Payload["project_id"].(string)
+			docID, _ := p.Payload["doc_id"].(string)
+			key := [2]string{projectID, docID}
+			if done[key] {
+				continue
+			}
+			done[key] = true
+			n, err := s.migrateDoc(ctx, job.embedder, st, projectID, docID)
+			if err != nil {
+				return fmt.Errorf("doc %s/%s: %w", projectID, docID, err)
+			}
+			job.update(func(m *migrationStatus) { m.MigratedDocs++; m.MigratedPoints += n })
+		}
+		if page.NextPageOffset == nil {
+			return nil
+		}
+		offset = page.NextPageOffset
+	}
+}
+
+// migrateDoc writes one document's points into the target, re-embedded with
+// emb or, if emb is nil, with their stored vectors.
+func (s *Server) migrateDoc(ctx context.Context, emb embed.Embedder, st migrationStatus, projectID, docID string) (int, error) {
+	unlock := s.docLocks.Lock(projectID + ":" + docID)
+	defer unlock()
+
+	f := qdrant.Filter{"must": []any{
+		map[string]any{"key": "project_id", "match": map[string]any{"value": projectID}},
+		map[string]any{"key": "doc_id", "match": map[string]any{"value": docID}},
+	}}
+	var points []qdrant.Point
+	var offset any
+	for {
+		page, err := s.store.Scroll(ctx, st.Source, f, 256, offset, emb == nil)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range page.Points {
+			points = append(points, qdrant.Point{ID: p.ID, Vector: p.Vector, Payload: p.Payload})
+		}
+		if page.NextPageOffset == nil {
+			break
+		}
+		offset = page.NextPageOffset
+	}
+	if emb != nil {
+		if err := embedPoints(ctx, emb, points); err != nil {
+			return 0, fmt.Errorf("%w: %w", errMigrationEmbed, err)
+		}
+	}
+	batching := store.BatchOptions{Size: s.cfg.Store.UpsertBatchSize, Parallelism: s.cfg.Store.UpsertParallelism}
+	if err := store.UpsertWithRetry(ctx, s.store.VectorStore, st.Target, points, batching, s.cfg.Store.UpsertRetries); err != nil {
+		return 0, fmt.Errorf("upsert: %w", err)
+	}
+	return len(points), nil
+}
+
+// embedPoints sets each point's vector to the embedding of its payload text.
+func embedPoints(ctx context.Context, emb embed.Embedder, points []qdrant.Point) error {
+	texts := make([]string, len(points))
+	for i, p := range points {
+		texts[i], _ = p.Payload["text"].(string)
+	}
+	vecs, err := emb.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	for i := range points {
+		points[i].Vector = vecs[i]
+	}
+	return nil
+}
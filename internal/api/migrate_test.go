@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func newAliasServer(t *testing.T, st store.VectorStore) http.Handler {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newServer(cfg, st)
+	waitHealth(t, h, "ok")
+	return h
+}
+
+func waitMigration(t *testing.T, h http.Handler, want string) migrationStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/migrations/current", nil))
+		var st migrationStatus
+		_ = json.Unmarshal(rec.Body.Bytes(), &st)
+		if st.State == want {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("migration: want state %q, last got %d %s", want, rec.Code, rec.Body.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMigration_ReembedsAndFlipsAlias(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	h := newAliasServer(t, st)
+	if got, _ := st.ResolveAlias(ctx, "kb"); got != "kb_chunks" {
+		t.Fatalf("expected the alias to be bootstrapped onto kb_chunks, got %q", got)
+	}
+
+	for _, doc := range []string{"doc1", "doc2"} {
+		req := ingestRequest{ProjectID: "proj1", DocID: doc, Title: doc, Content: "migration runbook " + doc, ACLPublic: true}
+		if code := doJSON(t, h, "/v1/docs/ingest", req, nil); code != http.StatusOK {
+			t.Fatalf("ingest %s: status %d", doc, code)
+		}
+	}
+
+	if code := doJSON(t, h, "/v1/admin/migrations", migrationRequest{Model: "next-model", Dim: 256}, nil); code != http.StatusAccepted {
+		t.Fatalf("start migration: status %d", code)
+	}
+	done := waitMigration(t, h, migrationCompleted)
+	if done.MigratedDocs != 2 || done.TotalPoints != done.MigratedPoints || done.Source != "kb_chunks" {
+		t.Fatalf("unexpected final status %+v", done)
+	}
+	if got, _ := st.ResolveAlias(ctx, "kb"); got != done.Target {
+		t.Fatalf("expected kb -> %s, got %q", done.Target, got)
+	}
+	if info, _ := st.CollectionInfo(ctx, "kb"); info.VectorSize != 256 {
+		t.Fatalf("expected 256-dim vectors behind the alias, got %+v", info)
+	}
+	if n, _ := st.Count(ctx, "kb_chunks", nil); n != done.TotalPoints {
+		t.Fatalf("the source collection must be kept, got %d points", n)
+	}
+
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	if res := searchAs(t, h, internal, "migration runbook"); len(res) != 2 {
+		t.Fatalf("expected both docs after the flip, got %+v", res)
+	}
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc3", Content: "after the flip", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest after flip: status %d", code)
+	}
+}
+
+func TestMigration_RequiresAlias(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Qdrant.Alias = ""
+	h := newServer(cfg, store.NewMemory())
+	waitHealth(t, h, "ok")
+	if code := doJSON(t, h, "/v1/admin/migrations", migrationRequest{Model: "next-model", Dim: 256}, nil); code != http.StatusConflict {
+		t.Fatalf("expected 409 without an alias, got %d", code)
+	}
+}
+
+// blockingStore stalls upserts into one collection until the context ends.
+type blockingStore struct {
+	*store.Memory
+	block string
+}
+
+func (b *blockingStore) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	if collection == b.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return b.Memory.Upsert(ctx, collection, points)
+}
+
+func TestMigration_AbortDropsTarget(t *testing.T) {
+	ctx := context.Background()
+	st := &blockingStore{Memory: store.NewMemory(), block: "kb_next"}
+	h := newAliasServer(t, st)
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "stuck", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+
+	if code := doJSON(t, h, "/v1/admin/migrations", migrationRequest{Model: "next-model", Dim: 256, Collection: "kb_next"}, nil); code != http.StatusAccepted {
+		t.Fatalf("start migration: status %d", code)
+	}
+	waitMigration(t, h, migrationRunning)
+	if code := doJSON(t, h, "/v1/admin/migrations", migrationRequest{Model: "other", Dim: 128}, nil); code != http.StatusConflict {
+		t.Fatalf("a second migration must be rejected, got %d", code)
+	}
+	var aborted migrationStatus
+	if code := doJSON(t, h, "/v1/admin/migrations/abort", nil, &aborted); code != http.StatusOK || aborted.State != migrationAborted {
+		t.Fatalf("abort: status %d %+v", code, aborted)
+	}
+	if got, _ := st.ResolveAlias(ctx, "kb"); got != "kb_chunks" {
+		t.Fatalf("abort must leave the alias alone, got %q", got)
+	}
+	if _, err := st.CollectionInfo(ctx, "kb_next"); !errors.Is(err, qdrant.ErrNotFound) {
+		t.Fatalf("abort must drop the target, got %v", err)
+	}
+}
+
+// rejectingStore fails upserts into one collection with a Qdrant 400 whose
+// body echoes a payload.
+type rejectingStore struct {
+	*store.Memory
+	reject string
+}
+
+func (s *rejectingStore) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	if collection == s.reject {
+		return &qdrant.Error{Op: "upsert", Status: http.StatusBadRequest, Kind: qdrant.ErrBadRequest, Body: `{"status":{"error":"payload acl_allow=secret-group"}}`}
+	}
+	return s.Memory.Upsert(ctx, collection, points)
+}
+
+func TestMigration_FailureHidesQdrantBody(t *testing.T) {
+	st := &rejectingStore{Memory: store.NewMemory(), reject: "kb_next"}
+	h := newAliasServer(t, st)
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "rejected", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+	if code := doJSON(t, h, "/v1/admin/migrations", migrationRequest{Model: "next-model", Dim: 256, Collection: "kb_next"}, nil); code != http.StatusAccepted {
+		t.Fatalf("start migration: status %d", code)
+	}
+	failed := waitMigration(t, h, migrationFailed)
+	if failed.Error != "migration_failed" || failed.ErrorDetail != "rejected" {
+		t.Fatalf("expected a stable code and reason, got %q %q", failed.Error, failed.ErrorDetail)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/migrations/current", nil))
+	if strings.Contains(rec.Body.String(), "secret-group") {
+		t.Fatalf("qdrant body leaked to client: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/embed"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+)
+
+// mirror copies writes addressed to from into the collection to while a
+// migration backfills it. Upserted points are re-embedded from their text
+// with embedder, or copied as they are if it is nil.
+type mirror struct {
+	from, to string
+	embedder embed.Embedder
+	// fail is called when a write reached the primary but not the target.
+	fail func(error)
+}
+
+// mirrorStore is the gateway's store: the configured backend plus the
+// mirror of the running migration, if any.
+type mirrorStore struct {
+	store.VectorStore
+	active *atomic.Pointer[mirror]
+}
+
+func newMirrorStore(st store.VectorStore) *mirrorStore {
+	return &mirrorStore{VectorStore: st, active: new(atomic.Pointer[mirror])}
+}
+
+func (ms *mirrorStore) startMirror(m *mirror) { ms.active.Store(m) }
+func (ms *mirrorStore) stopMirror()           { ms.active.Store(nil) }
+
+// aliases returns the backend as an AliasStore if it supports aliases.
+func (ms *mirrorStore) aliases() (store.AliasStore, bool) {
+	as, ok := ms.VectorStore.(store.AliasStore)
+	return as, ok
+}
+
+// snapshots returns the backend as a Snapshotter if it keeps snapshots.
+func (ms *mirrorStore) snapshots() (store.Snapshotter, bool) {
+	sn, ok := ms.VectorStore.(store.Snapshotter)
+	return sn, ok
+}
+
+func (ms *mirrorStore) target(collection string) *mirror {
+	if m := ms.active.Load(); m != nil && m.from == collection {
+		return m
+	}
+	return nil
+}
+
+func (ms *mirrorStore) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	if err := ms.VectorStore.Upsert(ctx, collection, points); err != nil {
+		return err
+	}
+	m := ms.target(collection)
+	if m == nil {
+		return nil
+	}
+	if m.embedder == nil {
+		if err := ms.VectorStore.Upsert(ctx, m.to, points); err != nil {
+			m.fail(fmt.Errorf("mirror upsert: %w", err))
+		}
+		return nil
+	}
+	copied := make([]qdrant.Point, len(points))
+	for i, p := range points {
+		copied[i] = qdrant.Point{ID: p.ID, Payload: p.Payload}
+	}
+	if err := embedPoints(ctx, m.embedder, copied); err != nil {
+		m.fail(fmt.Errorf("mirror %w: %w", errMigrationEmbed, err))
+		return nil
+	}
+	if err := ms.VectorStore.Upsert(ctx, m.to, copied); err != nil {
+		m.fail(fmt.Errorf("mirror upsert: %w", err))
+	}
+	return nil
+}
+
+func (ms *mirrorStore) SetPayload(ctx context.Context, collection string, payload map[string]any, filter qdrant.Filter) error {
+	if err := ms.VectorStore.SetPayload(ctx, collection, payload, filter); err != nil {
+		return err
+	}
+	if m := ms.target(collection); m != nil {
+		if err := ms.VectorStore.SetPayload(ctx, m.to, payload, filter); err != nil {
+			m.fail(fmt.Errorf("mirror set payload: %w", err))
+		}
+	}
+	return nil
+}
+
+func (ms *mirrorStore) DeleteByFilter(ctx context.Context, collection string, filter qdrant.Filter) error {
+	if err := ms.VectorStore.DeleteByFilter(ctx, collection, filter); err != nil {
+		return err
+	}
+	if m := ms.target(collection); m != nil {
+		if err := ms.VectorStore.DeleteByFilter(ctx, m.to, filter); err != nil {
+			m.fail(fmt.Errorf("mirror delete: %w", err))
+		}
+	}
+	return nil
+}
+
+// InTx keeps the mirror in place inside backend transactions.
+func (ms *mirrorStore) InTx(ctx context.Context, fn func(store.VectorStore) error) error {
+	return store.InTx(ctx, ms.VectorStore, func(tx store.VectorStore) error {
+		return fn(&mirrorStore{VectorStore: tx, active: ms.active})
+	})
+}
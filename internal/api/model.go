@@ -1,21 +1,27 @@
 package api
 
+import "github.com/HardMakabaka/KB-Gateway/internal/redact"
+
 type ChunkPayload struct {
-	ProjectID        string   `json:"project_id"`
-	DocID            string   `json:"doc_id"`
-	DocVersion       string   `json:"doc_version"`
-	DocVersionTS     int64    `json:"doc_version_ts"`
-	IsActive         bool     `json:"is_active"`
-	ChunkID          int      `json:"chunk_id"`
-	Source           string   `json:"source"`
-	Title            string   `json:"title"`
-	PathOrURL        string   `json:"path_or_url"`
-	Text             string   `json:"text"`
-	ContentHash      string   `json:"content_hash"`
-	ACLPublic        bool     `json:"acl_public"`
-	ACLExternalPublic bool    `json:"acl_external_public"`
-	ACLAllow         []string `json:"acl_allow"`
-	CreatedAt        int64    `json:"created_at"`
-	UpdatedAt        int64    `json:"updated_at"`
-	Deleted          bool     `json:"deleted"`
+	ProjectID         string         `json:"project_id"`
+	DocID             string         `json:"doc_id"`
+	DocVersion        string         `json:"doc_version"`
+	DocVersionTS      int64          `json:"doc_version_ts"`
+	IsActive          bool           `json:"is_active"`
+	ChunkID           int            `json:"chunk_id"`
+	Source            string         `json:"source"`
+	Title             string         `json:"title"`
+	PathOrURL         string         `json:"path_or_url"`
+	PathPrefixes      []string       `json:"path_prefixes"`
+	Text              string         `json:"text"`
+	Redactions        []redact.Span  `json:"redactions,omitempty"`
+	ContentHash       string         `json:"content_hash"`
+	ACLPublic         bool           `json:"acl_public"`
+	ACLExternalPublic bool           `json:"acl_external_public"`
+	ACLAllow          []string       `json:"acl_allow"`
+	ACLDeny           []string       `json:"acl_deny"`
+	CreatedAt         int64          `json:"created_at"`
+	UpdatedAt         int64          `json:"updated_at"`
+	Deleted           bool           `json:"deleted"`
+	Meta              map[string]any `json:"meta,omitempty"`
 }
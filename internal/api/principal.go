@@ -0,0 +1,72 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/auth"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// effectivePrincipal returns the principal a request acts as. With
+// authentication enabled it is derived from the bearer token; the body
+// principal is only honoured for service callers allowed to impersonate.
+// Without authentication (local dev) the body principal is trusted as before.
+// API key callers act as the service principal "apikey:<id>" and need the
+// impersonate scope to pass a body principal. Groups are expanded through the
+// group directory.
+func (s *Server) effectivePrincipal(r *http.Request, body types.Principal) (types.Principal, *apiError) {
+	pr, apiErr := s.requestPrincipal(r, body)
+	if apiErr != nil {
+		return types.Principal{}, apiErr
+	}
+	return s.expandGroups(pr), nil
+}
+
+// requestPrincipal is effectivePrincipal without group expansion.
+func (s *Server) requestPrincipal(r *http.Request, body types.Principal) (types.Principal, *apiError) {
+	if k, ok := apikey.FromContext(r.Context()); ok {
+		if bodyPrincipalEmpty(body) {
+			return types.Principal{Type: types.PrincipalService, ID: "apikey:" + k.ID}, nil
+		}
+		if !k.HasScope(apikey.ScopeImpersonate) {
+			return types.Principal{}, &apiError{Status: http.StatusForbidden, Code: "impersonation_not_allowed"}
+		}
+		if !auth.ValidType(body.Type) || body.ID == "" {
+			return types.Principal{}, &apiError{Status: http.StatusBadRequest, Code: "invalid_principal"}
+		}
+		log.Printf("apikey: key=%s impersonating %s %s (request %s)", k.ID, body.Type, body.ID, requestID(r))
+		return body, nil
+	}
+	if s.auth == nil {
+		return body, nil
+	}
+	caller, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return types.Principal{}, &apiError{Status: http.StatusUnauthorized, Code: "unauthorized"}
+	}
+	if bodyPrincipalEmpty(body) {
+		return caller, nil
+	}
+	if !s.auth.CanImpersonate(caller) {
+		return types.Principal{}, &apiError{Status: http.StatusForbidden, Code: "impersonation_not_allowed"}
+	}
+	if !auth.ValidType(body.Type) || body.ID == "" {
+		return types.Principal{}, &apiError{Status: http.StatusBadRequest, Code: "invalid_principal"}
+	}
+	log.Printf("auth: %s %s impersonating %s %s (request %s)", caller.Type, caller.ID, body.Type, body.ID, requestID(r))
+	return body, nil
+}
+
+func bodyPrincipalEmpty(p types.Principal) bool {
+	return p.Type == "" && p.ID == "" && len(p.Groups) == 0
+}
+
+// expandGroups replaces pr's groups with its effective groups.
+func (s *Server) expandGroups(pr types.Principal) types.Principal {
+	if s.groups == nil {
+		return pr
+	}
+	return s.groups.Expand(pr)
+}
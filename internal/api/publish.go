@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/audit"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+)
+
+// Publication states stored in the publish_status payload field.
+const (
+	publishRequested = "requested"
+	publishApproved  = "approved"
+	publishRevoked   = "revoked"
+)
+
+type publishAction string
+
+const (
+	publishActionRequest publishAction = "request"
+	publishActionApprove publishAction = "approve"
+	publishActionRevoke  publishAction = "revoke"
+)
+
+type publishRequest struct {
+	ProjectID  string `json:"project_id"`
+	DocID      string `json:"doc_id"`
+	DocVersion string `json:"doc_version"`
+	Reason     string `json:"reason"`
+}
+
+// publicationState is read from one point of a doc_version; the workflow
+// always updates every point of the version together.
+type publicationState struct {
+	Status         string
+	RequestedBy    string
+	ExternalPublic bool
+	Deleted        bool
+}
+
+func (p publicationState) fields() map[string]any {
+	return map[string]any{"acl_external_public": p.ExternalPublic, "publish_status": p.Status}
+}
+
+// handlePublish serves the external publication workflow for a doc_version:
+// an editor requests publication, a different publisher approves it (setting
+// acl_external_public=true on the version's points) and a publisher can revoke
+// it again. Every transition is written to the audit log.
+func (s *Server) handlePublish(action publishAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req publishRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+			return
+		}
+		if req.ProjectID == "" || req.DocID == "" || req.DocVersion == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
+			return
+		}
+		minRole := rbac.RolePublisher
+		if action == publishActionRequest {
+			minRole = rbac.RoleEditor
+		}
+		actor, apiErr := s.authorizePrincipal(r, req.ProjectID, minRole)
+		if apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+
+		unlock := s.docLocks.Lock(req.ProjectID + ":" + req.DocID)
+		defer unlock()
+
+		f := versionFilter(req.ProjectID, req.DocID, req.DocVersion)
+		before, found, err := s.publicationState(r.Context(), f)
+		if err != nil {
+			storeError("qdrant_scroll_failed", err).write(w)
+			return
+		}
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": "version_not_found"})
+			return
+		}
+
+		now := time.Now().UTC().Unix()
+		after := before
+		patch := map[string]any{}
+		switch action {
+		case publishActionRequest:
+			switch {
+			case before.Deleted:
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "version_deleted"})
+				return
+			case before.ExternalPublic:
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "already_published"})
+				return
+			case before.Status == publishRequested:
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "already_requested"})
+				return
+			}
+			after.Status = publishRequested
+			patch["publish_requested_by"] = actor.ID
+			patch["publish_requested_at"] = now
+		case publishActionApprove:
+			if before.Status != publishRequested || before.Deleted {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "no_pending_request"})
+				return
+			}
+			if before.RequestedBy == actor.ID && actor.ID != anonymousPrincipal.ID {
+				writeJSON(w, http.StatusForbidden, map[string]any{"error": "self_approval_not_allowed"})
+				return
+			}
+			after.Status = publishApproved
+			after.ExternalPublic = true
+			patch["publish_approved_by"] = actor.ID
+			patch["publish_approved_at"] = now
+			patch["updated_at"] = now
+		case publishActionRevoke:
+			if !before.ExternalPublic && before.Status != publishRequested {
+				writeJSON(w, http.StatusConflict, map[string]any{"error": "not_published"})
+				return
+			}
+			after.Status = publishRevoked
+			after.ExternalPublic = false
+			patch["publish_revoked_by"] = actor.ID
+			patch["publish_revoked_at"] = now
+			patch["updated_at"] = now
+		}
+		for k, v := range after.fields() {
+			patch[k] = v
+		}
+
+		if err := s.store.SetPayload(r.Context(), s.collection, patch, f); err != nil {
+			storeError("qdrant_set_payload_failed", err).write(w)
+			return
+		}
+		s.audit.Record(audit.Event{
+			Action:     "publish." + string(action),
+			ActorType:  string(actor.Type),
+			ActorID:    actor.ID,
+			ProjectID:  req.ProjectID,
+			DocID:      req.DocID,
+			DocVersion: req.DocVersion,
+			Before:     before.fields(),
+			After:      after.fields(),
+			Details:    map[string]any{"reason": req.Reason},
+			RequestID:  requestID(r),
+		})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "publish_status": after.Status, "acl_external_public": after.ExternalPublic})
+	}
+}
+
+func (s *Server) publicationState(ctx context.Context, f qdrant.Filter) (publicationState, bool, error) {
+	res, err := s.store.Scroll(ctx, s.collection, f, 1, nil, false)
+	if err != nil || len(res.Points) == 0 {
+		return publicationState{}, false, err
+	}
+	p := res.Points[0].Payload
+	return publicationState{
+		Status:         toString(p["publish_status"]),
+		RequestedBy:    toString(p["publish_requested_by"]),
+		ExternalPublic: p["acl_external_public"] == true,
+		Deleted:        p["deleted"] == true,
+	}, true, nil
+}
+
+func versionFilter(projectID, docID, docVersion string) qdrant.Filter {
+	return qdrant.Filter{"must": []any{
+		matchValue("project_id", projectID),
+		matchValue("doc_id", docID),
+		matchValue("doc_version", docVersion),
+	}}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/audit"
+	"github.com/HardMakabaka/KB-Gateway/internal/auth"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+var (
+	editor     = types.Principal{Type: types.PrincipalInternalUser, ID: "alice", Groups: []string{"editors"}}
+	publisher  = types.Principal{Type: types.PrincipalInternalUser, ID: "bob", Groups: []string{"publishers"}}
+	publisher2 = types.Principal{Type: types.PrincipalInternalUser, ID: "carol", Groups: []string{"publishers"}}
+)
+
+// newRoleServer returns a server with a role policy for proj1 and doc1 stored
+// in versions v1 and v2, writing audit events to the returned buffer.
+func newRoleServer(t *testing.T) (*Server, *store.Memory, *bytes.Buffer) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"projects":{"proj1":{"groups":{"editors":"editor","publishers":"publisher"}}}}`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := rbac.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemory()
+	ctx := context.Background()
+	if err := st.EnsureCollection(ctx, "kb_chunks", 2); err != nil {
+		t.Fatal(err)
+	}
+	var points []qdrant.Point
+	for i, v := range []string{"v1", "v1", "v2"} {
+		points = append(points, qdrant.Point{ID: i + 1, Vector: []float32{1, 0}, Payload: map[string]any{
+			"project_id": "proj1", "doc_id": "doc1", "doc_version": v, "chunk_id": i,
+			"is_active": v == "v2", "deleted": false,
+			"acl_public": false, "acl_allow": []any{"eng"}, "acl_deny": []any{}, "acl_external_public": false,
+		}})
+	}
+	if err := st.Upsert(ctx, "kb_chunks", points); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	s := &Server{store: newMirrorStore(st), collection: "kb_chunks", rbac: a, audit: audit.NewWriter(&buf)}
+	return s, st, &buf
+}
+
+// callAs serves body to h as the authenticated principal pr.
+func callAs(t *testing.T, h http.HandlerFunc, pr types.Principal, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	r = r.WithContext(auth.WithPrincipal(r.Context(), pr))
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	return rec
+}
+
+func auditEvents(t *testing.T, buf *bytes.Buffer) []audit.Event {
+	t.Helper()
+	var out []audit.Event
+	sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for sc.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q: %v", sc.Text(), err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func externalPublic(t *testing.T, st *store.Memory, version string) bool {
+	t.Helper()
+	res, err := st.Scroll(context.Background(), "kb_chunks", versionFilter("proj1", "doc1", version), 10, nil, false)
+	if err != nil || len(res.Points) == 0 {
+		t.Fatalf("scroll %s: %v", version, err)
+	}
+	for _, p := range res.Points {
+		if p.Payload["acl_external_public"] != res.Points[0].Payload["acl_external_public"] {
+			t.Fatalf("points of %s disagree on acl_external_public", version)
+		}
+	}
+	return res.Points[0].Payload["acl_external_public"] == true
+}
+
+func TestPublish_RequestApproveRevoke(t *testing.T) {
+	s, st, buf := newRoleServer(t)
+	req := publishRequest{ProjectID: "proj1", DocID: "doc1", DocVersion: "v1", Reason: "customer FAQ"}
+
+	if rec := callAs(t, s.handlePublish(publishActionRequest), editor, req); rec.Code != http.StatusOK {
+		t.Fatalf("request: status %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := callAs(t, s.handlePublish(publishActionApprove), editor, req); rec.Code != http.StatusForbidden {
+		t.Fatalf("an editor must not approve, got %d", rec.Code)
+	}
+	if externalPublic(t, st, "v1") {
+		t.Fatal("a pending request must not publish")
+	}
+	if rec := callAs(t, s.handlePublish(publishActionApprove), publisher, req); rec.Code != http.StatusOK {
+		t.Fatalf("approve: status %d %s", rec.Code, rec.Body.String())
+	}
+	if !externalPublic(t, st, "v1") || externalPublic(t, st, "v2") {
+		t.Fatal("approval must publish exactly the requested version")
+	}
+	if rec := callAs(t, s.handlePublish(publishActionRevoke), editor, req); rec.Code != http.StatusForbidden {
+		t.Fatalf("an editor must not revoke, got %d", rec.Code)
+	}
+	if rec := callAs(t, s.handlePublish(publishActionRevoke), publisher2, req); rec.Code != http.StatusOK {
+		t.Fatalf("revoke: status %d %s", rec.Code, rec.Body.String())
+	}
+	if externalPublic(t, st, "v1") {
+		t.Fatal("revoke must unpublish the version")
+	}
+
+	events := auditEvents(t, buf)
+	want := []struct{ action, actor string }{{"publish.request", "alice"}, {"publish.approve", "bob"}, {"publish.revoke", "carol"}}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d audit events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Action != w.action || e.ActorID != w.actor || e.DocVersion != "v1" || e.Details["reason"] != "customer FAQ" {
+			t.Fatalf("event %d: unexpected %+v", i, e)
+		}
+	}
+	if events[1].Before["acl_external_public"] != false || events[1].After["acl_external_public"] != true || events[1].After["publish_status"] != publishApproved {
+		t.Fatalf("approval must record the before/after state, got %+v", events[1])
+	}
+}
+
+func TestPublish_SelfApprovalRejected(t *testing.T) {
+	s, st, buf := newRoleServer(t)
+	req := publishRequest{ProjectID: "proj1", DocID: "doc1", DocVersion: "v2"}
+	if rec := callAs(t, s.handlePublish(publishActionRequest), publisher, req); rec.Code != http.StatusOK {
+		t.Fatalf("request: status %d %s", rec.Code, rec.Body.String())
+	}
+	rec := callAs(t, s.handlePublish(publishActionApprove), publisher, req)
+	if rec.Code != http.StatusForbidden || !bytes.Contains(rec.Body.Bytes(), []byte("self_approval_not_allowed")) {
+		t.Fatalf("expected self approval to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+	if externalPublic(t, st, "v2") {
+		t.Fatal("a rejected approval must not publish")
+	}
+	if events := auditEvents(t, buf); len(events) != 1 || events[0].Action != "publish.request" {
+		t.Fatalf("only the request may be audited, got %+v", events)
+	}
+}
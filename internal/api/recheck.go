@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/acl"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// hitViolation re-checks a search hit in Go, independently of the Qdrant
+// filter, and returns why the principal must not see it ("" if permitted).
+func hitViolation(pr types.Principal, scope []string, p map[string]any) string {
+	project := toString(p["project_id"])
+	inScope := false
+	for _, s := range scope {
+		if s == project {
+			inScope = true
+			break
+		}
+	}
+	switch {
+	case !inScope:
+		return "project_out_of_scope"
+	case p["is_active"] != true:
+		return "inactive_version"
+	case p["deleted"] != false:
+		return "deleted"
+	case !acl.Allowed(pr, docACLFromPayload(p)):
+		return "acl_denied"
+	}
+	return ""
+}
+
+func docACLFromPayload(p map[string]any) acl.DocACL {
+	return acl.DocACL{
+		Public:         p["acl_public"] == true,
+		ExternalPublic: p["acl_external_public"] == true,
+		Allow:          toStrings(p["acl_allow"]),
+		Deny:           toStrings(p["acl_deny"]),
+	}
+}
+
+func toStrings(v any) []string {
+	switch x := v.(type) {
+	case []string:
+		return x
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, it := range x {
+			if s, ok := it.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// logSecurityEvent records a hit that passed the Qdrant filter but failed the
+// Go re-check. Any occurrence means the filter and ACL semantics disagree.
+func logSecurityEvent(ctx context.Context, pr types.Principal, pointID any, p map[string]any, reason string) {
+	log.Printf("security: dropped search hit point=%v project=%s doc=%s version=%s reason=%s principal=%s:%s (request %s)",
+		pointID, toString(p["project_id"]), toString(p["doc_id"]), toString(p["doc_version"]), reason,
+		pr.Type, pr.ID, middleware.GetReqID(ctx))
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requiredIndexes are the payload fields the base, ACL, version and user
+// filters match on. Creating an existing index is a no-op in Qdrant.
+var requiredIndexes = []struct{ field, schema string }{
+	{"project_id", "keyword"},
+	{"doc_id", "keyword"},
+	{"doc_version", "keyword"},
+	{"is_active", "bool"},
+	{"deleted", "bool"},
+	{"acl_public", "bool"},
+	{"acl_external_public", "bool"},
+	{"acl_allow", "keyword"},
+	{"acl_deny", "keyword"},
+	{"source", "keyword"},
+	{"path_prefixes", "keyword"},
+	{"created_at", "integer"},
+	{"updated_at", "integer"},
+}
+
+const schemaRetryInterval = 5 * time.Second
+
+// schemaMismatchError means the collection exists with a vector
+// configuration the embedder cannot use. Retrying will not fix it.
+type schemaMismatchError struct {
+	collection string
+	wantSize   int
+	gotSize    int
+	gotDist    string
+}
+
+func (e *schemaMismatchError) Error() string {
+	return fmt.Sprintf("collection %s has %d-dim %s vectors, embedder needs %d-dim Cosine",
+		e.collection, e.gotSize, e.gotDist, e.wantSize)
+}
+
+// schemaState is what /healthz reports about the collection.
+type schemaState struct {
+	mu    sync.RWMutex
+	ready bool
+	err   error
+}
+
+func (st *schemaState) set(ready bool, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.ready, st.err = ready, err
+}
+
+func (st *schemaState) get() (bool, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.ready, st.err
+}
+
+// ensureSchema creates the collection and its payload indexes and verifies
+// that an existing collection matches the embedder. In alias mode a missing
+// alias is created pointing at the configured collection.
+func (s *Server) ensureSchema(ctx context.Context) error {
+	collection, dim := s.collection, s.embedder.Dim()
+	if err := s.ensureCollection(ctx, dim); err != nil {
+		return fmt.Errorf("ensure collection: %w", err)
+	}
+	info, err := s.store.CollectionInfo(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("collection info: %w", err)
+	}
+	if info.VectorSize != dim || !strings.EqualFold(info.Distance, "Cosine") {
+		return &schemaMismatchError{collection: collection, wantSize: dim, gotSize: info.VectorSize, gotDist: info.Distance}
+	}
+	return s.createIndexes(ctx, collection)
+}
+
+func (s *Server) ensureCollection(ctx context.Context, dim int) error {
+	alias := s.cfg.Qdrant.Alias
+	as, ok := s.store.aliases()
+	if alias == "" || !ok {
+		return s.store.EnsureCollection(ctx, s.collection, dim)
+	}
+	target, err := as.ResolveAlias(ctx, alias)
+	if err != nil || target != "" {
+		return err
+	}
+	if err := s.store.EnsureCollection(ctx, s.cfg.Qdrant.Collection, dim); err != nil {
+		return err
+	}
+	log.Printf("creating alias %s -> %s", alias, s.cfg.Qdrant.Collection)
+	return as.SwitchAlias(ctx, alias, s.cfg.Qdrant.Collection)
+}
+
+// createIndexes creates the required and metadata payload indexes on
+// collection. Creating an existing index is a no-op.
+func (s *Server) createIndexes(ctx context.Context, collection string) error {
+	var errs []error
+	for _, idx := range requiredIndexes {
+		if err := s.store.CreatePayloadIndex(ctx, collection, idx.field, idx.schema); err != nil {
+			errs = append(errs, fmt.Errorf("payload index %s: %w", idx.field, err))
+		}
+	}
+	for field, typ := range s.metadata.IndexedFields() {
+		if err := s.store.CreatePayloadIndex(ctx, collection, field, typ.IndexSchema()); err != nil {
+			errs = append(errs, fmt.Errorf("payload index %s: %w", field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// initSchema runs ensureSchema until it succeeds, so the gateway can start
+// before the store does. A mismatch is permanent and marks the gateway
+// unhealthy instead.
+func (s *Server) initSchema() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := s.ensureSchema(ctx)
+		cancel()
+		var mismatch *schemaMismatchError
+		switch {
+		case err == nil:
+			s.schema.set(true, nil)
+			return
+		case errors.As(err, &mismatch):
+			log.Printf("error: %v; reporting unhealthy", err)
+			s.schema.set(false, err)
+			return
+		}
+		log.Printf("ensure collection schema failed, retrying in %s: %v", schemaRetryInterval, err)
+		time.Sleep(schemaRetryInterval)
+	}
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ready, err := s.schema.get()
+	switch {
+	case err != nil:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unhealthy", "error": "collection_mismatch", "detail": err.Error()})
+	case !ready:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "starting"})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	}
+}
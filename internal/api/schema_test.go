@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+)
+
+type indexRecorder struct {
+	*store.Memory
+	mu      sync.Mutex
+	indexes map[string]string
+}
+
+func (r *indexRecorder) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexes[field] = schema
+	return r.Memory.CreatePayloadIndex(ctx, collection, field, schema)
+}
+
+func waitHealth(t *testing.T, h http.Handler, want string) int {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var body struct {
+			Status string `json:"status"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if body.Status == want {
+			return rec.Code
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("healthz: want status %q, last got %d %s", want, rec.Code, rec.Body.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchema_CreatesRequiredIndexes(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &indexRecorder{Memory: store.NewMemory(), indexes: map[string]string{}}
+	h := newServer(cfg, st)
+	if code := waitHealth(t, h, "ok"); code != http.StatusOK {
+		t.Fatalf("healthy server returned %d", code)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, idx := range requiredIndexes {
+		if st.indexes[idx.field] != idx.schema {
+			t.Fatalf("expected %s index on %s, got %v", idx.schema, idx.field, st.indexes)
+		}
+	}
+}
+
+func TestSchema_DimensionMismatchIsUnhealthy(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemory()
+	if err := st.EnsureCollection(context.Background(), cfg.Qdrant.Collection, 768); err != nil {
+		t.Fatal(err)
+	}
+	h := newServer(cfg, st)
+	if code := waitHealth(t, h, "unhealthy"); code != http.StatusServiceUnavailable {
+		t.Fatalf("mismatched collection should be unhealthy, got %d", code)
+	}
+}
@@ -3,71 +3,304 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/audit"
+	"github.com/HardMakabaka/KB-Gateway/internal/auth"
 	"github.com/HardMakabaka/KB-Gateway/internal/chunk"
 	"github.com/HardMakabaka/KB-Gateway/internal/config"
 	"github.com/HardMakabaka/KB-Gateway/internal/embed"
+	"github.com/HardMakabaka/KB-Gateway/internal/groups"
+	"github.com/HardMakabaka/KB-Gateway/internal/llm"
+	"github.com/HardMakabaka/KB-Gateway/internal/metadata"
 	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/internal/redact"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Server struct {
 	cfg      config.Config
-	qdrant   *qdrant.Client
-	embedder embed.Embedder
+	store    *mirrorStore
+	embedder *embed.Swappable
+	llm      llm.Completer
+	auth     *auth.Authenticator
+	apiKeys  *apikey.Store
+	rbac     *rbac.Authorizer
+	groups   *groups.Resolver
+	audit    *audit.Logger
 	chunkCfg chunk.Config
 	docLocks KeyedMutex
+	metadata *metadata.Schema
+	redactor *redact.Redactor
+
+	queryVectors *vectorCache
+	schema       schemaState
+
+	// collection is the alias or collection every request addresses.
+	collection  string
+	newEmbedder func(config.EmbedConfig) embed.Embedder
+	migration   migrationState
 }
 
 func NewServer(cfg config.Config) http.Handler {
-	s := &Server{cfg: cfg}
-	s.qdrant = qdrant.New(cfg.Qdrant.URL, cfg.Qdrant.Timeout)
+	st, err := openStore(cfg)
+	if err != nil {
+		log.Fatalf("store: %v", err)
+	}
+	return newServer(cfg, st)
+}
 
+func newEmbedder(cfg config.EmbedConfig) embed.Embedder {
 	// v1: use fake embedder if no API key configured to keep local dev unblocked.
-	if cfg.Embed.APIKey == "" {
-		log.Printf("warning: OPENAI_API_KEY not set; using fake embedder")
-		s.embedder = embed.NewFake(384)
+	if cfg.APIKey == "" {
+		log.Printf("warning: OPENAI_API_KEY not set; using fake embedder (model %s, dim %d)", cfg.Model, cfg.Dim)
+		return embed.NewFake(cfg.Dim)
+	}
+	// TODO: implement OpenAI embedder
+	log.Printf("warning: OpenAI embedder not implemented yet; using fake embedder (model %s, dim %d)", cfg.Model, cfg.Dim)
+	return embed.NewFake(cfg.Dim)
+}
+
+func openStore(cfg config.Config) (store.VectorStore, error) {
+	switch cfg.Store.Backend {
+	case "", "qdrant":
+		policy := qdrant.RetryPolicy{
+			MaxRetries:       cfg.Qdrant.MaxRetries,
+			Backoff:          cfg.Qdrant.RetryBackoff,
+			MaxBackoff:       cfg.Qdrant.RetryMaxBackoff,
+			BreakerThreshold: cfg.Qdrant.BreakerThreshold,
+			BreakerCooldown:  cfg.Qdrant.BreakerCooldown,
+		}
+		switch cfg.Qdrant.Transport {
+		case "", "http":
+			return qdrant.New(cfg.Qdrant.URL, cfg.Qdrant.Timeout).WithRetryPolicy(policy), nil
+		case "grpc":
+			c, err := qdrant.NewGRPC(cfg.Qdrant.GRPCAddr, cfg.Qdrant.Timeout)
+			if err != nil {
+				return nil, err
+			}
+			return c.WithRetryPolicy(policy), nil
+		default:
+			return nil, fmt.Errorf("unknown KBG_QDRANT_TRANSPORT %q", cfg.Qdrant.Transport)
+		}
+	case "postgres":
+		return store.OpenPostgres(context.Background(), cfg.Store.PostgresDSN)
+	case "embedded":
+		return store.OpenBolt(cfg.Store.EmbeddedPath)
+	case "memory":
+		log.Printf("warning: KBG_STORE_BACKEND=memory; nothing is persisted")
+		return store.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown KBG_STORE_BACKEND %q", cfg.Store.Backend)
+	}
+}
+
+func newServer(cfg config.Config, st store.VectorStore) http.Handler {
+	s := &Server{cfg: cfg, queryVectors: newVectorCache(1024)}
+	s.store = newMirrorStore(st)
+	if _, ok := s.store.aliases(); !ok && cfg.Qdrant.Alias != "" {
+		log.Printf("warning: store backend %q has no collection aliases; using %s directly", cfg.Store.Backend, cfg.Qdrant.Collection)
+		s.cfg.Qdrant.Alias = ""
+	}
+	s.collection = s.cfg.Qdrant.Collection
+	if s.cfg.Qdrant.Alias != "" {
+		s.collection = s.cfg.Qdrant.Alias
+	}
+	s.newEmbedder = newEmbedder
+	s.embedder = embed.NewSwappable(newEmbedder(cfg.Embed))
+
+	if cfg.Metadata.SchemaFile != "" {
+		schema, err := metadata.Load(cfg.Metadata.SchemaFile)
+		if err != nil {
+			log.Fatalf("load metadata schema: %v", err)
+		}
+		s.metadata = schema
+	} else {
+		log.Printf("warning: KBG_METADATA_SCHEMA_FILE not set; metadata is shape-checked only and not indexed")
+	}
+
+	if cfg.Redact.RulesFile != "" {
+		red, err := redact.Load(cfg.Redact.RulesFile)
+		if err != nil {
+			log.Fatalf("redaction rules: %v", err)
+		}
+		s.redactor = red
+	} else {
+		red, err := redact.New(redact.DefaultRules)
+		if err != nil {
+			log.Fatalf("redaction rules: %v", err)
+		}
+		s.redactor = red
+	}
+
+	// Same convention as the embedder: without an API key, answers come from a local stub.
+	if cfg.LLM.APIKey == "" {
+		log.Printf("warning: KBG_LLM_API_KEY not set; using fake LLM for /v1/answer")
+		s.llm = llm.NewFake()
+	} else {
+		s.llm = llm.NewOpenAI(cfg.LLM.BaseURL, cfg.LLM.APIKey, cfg.LLM.Model, cfg.LLM.Timeout)
+	}
+
+	if cfg.Auth.Enabled() {
+		v, err := auth.NewVerifier(auth.VerifierConfig{
+			HS256Secret:   cfg.Auth.JWTHS256Secret,
+			PublicKeyFile: cfg.Auth.JWTPublicKeyFile,
+			JWKSFile:      cfg.Auth.JWKSFile,
+			Issuer:        cfg.Auth.Issuer,
+			Audience:      cfg.Auth.Audience,
+			Leeway:        cfg.Auth.Leeway,
+		})
+		if err != nil {
+			log.Fatalf("auth: %v", err)
+		}
+		s.auth = auth.NewAuthenticator(v, cfg.Auth.PrincipalTypeClaim, cfg.Auth.GroupsClaim, cfg.Auth.Impersonators)
 	} else {
-		// TODO: implement OpenAI embedder
-		log.Printf("warning: OpenAI embedder not implemented yet; using fake embedder")
-		s.embedder = embed.NewFake(384)
+		log.Printf("warning: no JWT keys configured; authentication disabled and request principals are trusted")
 	}
 
+	if cfg.Auth.APIKeysFile != "" {
+		store, err := apikey.Open(cfg.Auth.APIKeysFile)
+		if err != nil {
+			log.Fatalf("api keys: %v", err)
+		}
+		s.apiKeys = store
+	} else if s.auth == nil {
+		log.Printf("warning: KBG_AUTH_API_KEYS_FILE not set; write endpoints are unauthenticated")
+	}
+
+	if cfg.Auth.PolicyFile != "" {
+		if s.auth == nil && s.apiKeys == nil {
+			// No caller could ever prove a principal the policy grants a role.
+			log.Fatalf("rbac policy: KBG_AUTH_POLICY_FILE needs JWT keys or KBG_AUTH_API_KEYS_FILE")
+		}
+		a, err := rbac.Load(cfg.Auth.PolicyFile)
+		if err != nil {
+			log.Fatalf("rbac policy: %v", err)
+		}
+		s.rbac = a
+		go a.Watch(context.Background(), cfg.Auth.PolicyReloadInterval)
+	} else if s.auth != nil {
+		log.Printf("warning: KBG_AUTH_POLICY_FILE not set; JWT principals hold no roles and are denied role-checked endpoints")
+	} else {
+		log.Printf("warning: KBG_AUTH_POLICY_FILE not set; per-project authorization disabled")
+	}
+
+	resolver, err := groups.NewResolver(groups.Config{
+		File:      cfg.Groups.File,
+		LDIFFile:  cfg.Groups.LDIFFile,
+		Hierarchy: cfg.Groups.Hierarchy,
+		CacheTTL:  cfg.Groups.CacheTTL,
+	})
+	if err != nil {
+		log.Fatalf("groups: %v", err)
+	}
+	s.groups = resolver
+	go resolver.Watch(context.Background(), cfg.Groups.SyncInterval)
+
+	auditLog, err := audit.Open(cfg.Audit.File)
+	if err != nil {
+		log.Fatalf("audit log: %v", err)
+	}
+	s.audit = auditLog
+
 	s.chunkCfg = chunk.Config{MaxChars: cfg.Chunk.MaxChars, Overlap: cfg.Chunk.Overlap, MinChars: cfg.Chunk.MinChars, HardLimit: cfg.Chunk.HardLimit}
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
 
-	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r.With(middleware.Timeout(requestTimeout)).Get("/healthz", s.handleHealthz)
 
 	r.Route("/v1", func(r chi.Router) {
-		r.Post("/docs/ingest", s.handleIngest)
-		r.Post("/docs/activate", s.handleActivate)
-		r.Post("/docs/delete", s.handleDelete)
-		r.Post("/docs/rollback", s.handleRollback)
-		r.Post("/search", s.handleSearch)
+		r.Use(s.authenticate)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(requestTimeout))
+			r.With(s.requireScope(apikey.ScopeIngest)).Post("/docs/ingest", s.handleIngest)
+			r.With(s.requireScope(apikey.ScopeActivate)).Post("/docs/activate", s.handleActivate)
+			r.With(s.requireScope(apikey.ScopeDelete)).Post("/docs/delete", s.handleDelete)
+			r.With(s.requireScope(apikey.ScopeActivate)).Post("/docs/rollback", s.handleRollback)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/docs/acl", s.handleDocACL)
+			r.Post("/docs/publish/request", s.handlePublish(publishActionRequest))
+			r.Post("/docs/publish/approve", s.handlePublish(publishActionApprove))
+			r.Post("/docs/publish/revoke", s.handlePublish(publishActionRevoke))
+			r.With(s.requireScope(apikey.ScopeSearch)).Post("/search", s.handleSearch)
+			r.With(s.requireScope(apikey.ScopeSearch)).Post("/answer", s.handleAnswer)
+			r.With(s.requireScope(apikey.ScopeSearch)).Post("/principal/groups", s.handleExplainGroups)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/migrations", s.handleStartMigration)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Get("/admin/migrations/current", s.handleGetMigration)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/migrations/abort", s.handleAbortMigration)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Get("/admin/collections", s.handleListCollections)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/collections", s.handleBuildCollection)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Delete("/admin/collections/{name}", s.handleDeleteCollection)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/alias", s.handleSwitchAlias)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Get("/admin/snapshots", s.handleListSnapshots)
+			r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/snapshots", s.handleCreateSnapshot)
+		})
+
+		// Exports and imports run as long as the client stays connected.
+		r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/export", s.handleExport)
+		r.With(s.requireScope(apikey.ScopeAdmin)).Post("/admin/import", s.handleImport)
+
+		// Streams have already sent their headers when a deadline hits, so
+		// they get their own, longer one that just ends the stream instead of
+		// chi's 504 response.
+		r.Group(func(r chi.Router) {
+			r.Use(withDeadline(cfg.HTTP.StreamTimeout))
+			r.With(s.requireScope(apikey.ScopeSearch)).Post("/search/stream", s.handleSearchStream)
+			r.With(s.requireScope(apikey.ScopeSearch)).Post("/answer/stream", s.handleAnswerStream)
+		})
 	})
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := s.qdrant.EnsureCollection(ctx, cfg.Qdrant.Collection, s.embedder.Dim()); err != nil {
-			log.Printf("ensure qdrant collection failed: %v", err)
-		}
-		// Ensure deleted=false is present for new docs; we rely on matchBool("deleted", false).
-		// (If missing, qdrant match will not match; v1 requires deleted field to be always set.)
-	}()
+	// Ensure deleted=false is present for new docs; we rely on matchBool("deleted", false).
+	// (If missing, qdrant match will not match; v1 requires deleted field to be always set.)
+	go s.initSchema()
 
 	return r
 }
 
+// requestTimeout bounds every non-streaming request.
+const requestTimeout = 30 * time.Second
+
+// withDeadline cancels the request context after d without writing a
+// response; handlers notice through the context and stop.
+func withDeadline(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiError is an error response produced by logic shared between handlers.
+type apiError struct {
+	Status int
+	Code   string
+	Detail string
+}
+
+func (e *apiError) write(w http.ResponseWriter) {
+	body := map[string]any{"error": e.Code}
+	if e.Detail != "" {
+		body["detail"] = e.Detail
+	}
+	writeJSON(w, e.Status, body)
+}
+
+func requestID(r *http.Request) string {
+	return middleware.GetReqID(r.Context())
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
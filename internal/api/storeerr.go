@@ -0,0 +1,33 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// storeError maps a vector store failure to an API error. The cause is only
+// logged: Qdrant response bodies can echo payloads and filters, so clients
+// get a stable code and, for classified failures, a short reason.
+func storeError(code string, err error) *apiError {
+	log.Printf("%s: %v", code, err)
+	return classifyStoreError(code, err)
+}
+
+// classifyStoreError is storeError without logging, for callers that
+// already logged the cause.
+func classifyStoreError(code string, err error) *apiError {
+	switch {
+	case errors.Is(err, qdrant.ErrUnavailable):
+		return &apiError{Status: http.StatusServiceUnavailable, Code: "store_unavailable"}
+	case errors.Is(err, qdrant.ErrConflict):
+		return &apiError{Status: http.StatusConflict, Code: code, Detail: "conflict"}
+	case errors.Is(err, qdrant.ErrNotFound):
+		return &apiError{Status: http.StatusBadGateway, Code: code, Detail: "not_found"}
+	case errors.Is(err, qdrant.ErrBadRequest):
+		return &apiError{Status: http.StatusBadGateway, Code: code, Detail: "rejected"}
+	}
+	return &apiError{Status: http.StatusBadGateway, Code: code}
+}
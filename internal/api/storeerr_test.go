@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func TestStoreErrors_AreMappedWithoutQdrantBodies(t *testing.T) {
+	cases := []struct {
+		status   int
+		wantCode int
+		wantErr  string
+	}{
+		{http.StatusServiceUnavailable, http.StatusServiceUnavailable, `"error":"store_unavailable"`},
+		{http.StatusBadRequest, http.StatusBadGateway, `"detail":"rejected"`},
+		{http.StatusInternalServerError, http.StatusBadGateway, `"error":"qdrant_search_failed"`},
+	}
+	for _, tc := range cases {
+		qd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"status":{"error":"payload acl_allow=secret-group"}}`, tc.status)
+		}))
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := qdrant.New(qd.URL, time.Second).WithRetryPolicy(qdrant.RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond})
+		h := newServer(cfg, c)
+
+		req := searchRequest{Query: "q", ProjectScope: []string{"proj1"}, Principal: types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}}
+		b, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/search", bytes.NewReader(b)))
+		qd.Close()
+
+		if rec.Code != tc.wantCode || !strings.Contains(rec.Body.String(), tc.wantErr) {
+			t.Fatalf("qdrant %d: got %d %s", tc.status, rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "secret-group") {
+			t.Fatalf("qdrant body leaked to client: %s", rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/llm"
+)
+
+// sseWriter writes server-sent events and flushes after each one so clients
+// see tokens as they are produced.
+type sseWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &sseWriter{w: w, f: f}, true
+}
+
+func (s *sseWriter) event(name string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
+
+type answerDone struct {
+	Answer        string     `json:"answer"`
+	Refused       bool       `json:"refused"`
+	RefusalReason string     `json:"refusal_reason,omitempty"`
+	Cited         []int      `json:"cited"`
+	Model         string     `json:"model,omitempty"`
+	Usage         *llm.Usage `json:"usage,omitempty"`
+	LatencyMS     int64      `json:"latency_ms"`
+}
+
+// handleAnswerStream is the SSE variant of handleAnswer. Events, in order:
+// "citations" once, "token" per answer delta, then "done" (or "error").
+// Validation and retrieval failures are reported as plain JSON errors before
+// the stream starts.
+func (s *Server) handleAnswerStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+	req, ok := s.decodeAnswerRequest(w, r)
+	if !ok {
+		return
+	}
+	sources, apiErr := s.retrieveSources(ctx, req)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "streaming_unsupported"})
+		return
+	}
+	cites := buildCitations(sources)
+	if err := sse.event("citations", map[string]any{"citations": cites}); err != nil {
+		return
+	}
+	if len(sources) == 0 {
+		_ = sse.event("done", answerDone{
+			Refused:       true,
+			RefusalReason: "no_permitted_context",
+			Cited:         []int{},
+			LatencyMS:     time.Since(start).Milliseconds(),
+		})
+		return
+	}
+
+	comp, err := s.llm.Stream(ctx, buildAnswerPrompt(req.Query, sources), func(delta string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return sse.event("token", map[string]any{"text": delta})
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// Client went away (or the request timed out); nothing left to tell it.
+			log.Printf("answer stream: cancelled: %v", ctx.Err())
+			return
+		}
+		log.Printf("answer stream: llm completion failed: %v", err)
+		_ = sse.event("error", map[string]any{"error": "llm_failed"})
+		return
+	}
+
+	cited := []int{}
+	for _, c := range markCited(cites, comp.Content) {
+		if c.Cited {
+			cited = append(cited, c.Index)
+		}
+	}
+	_ = sse.event("done", answerDone{
+		Answer:    comp.Content,
+		Cited:     cited,
+		Model:     comp.Model,
+		Usage:     &comp.Usage,
+		LatencyMS: time.Since(start).Milliseconds(),
+	})
+}
+
+// handleSearchStream emits each hit as a "result" event followed by "done"
+// with next_cursor and latency.
+func (s *Server) handleSearchStream(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var req searchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	if (strings.TrimSpace(req.Query) == "" && req.Cursor == "") || len(req.ProjectScope) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields"})
+		return
+	}
+	pr, scope, apiErr := s.searchPrincipal(r, req.Principal, req.ProjectScope)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	req.Principal = pr
+	req.ProjectScope = scope
+	resp, apiErr := s.search(r.Context(), req)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	sse, ok := newSSEWriter(w)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "streaming_unsupported"})
+		return
+	}
+	for _, res := range resp.Results {
+		if r.Context().Err() != nil {
+			return
+		}
+		if err := sse.event("result", res); err != nil {
+			return
+		}
+	}
+	_ = sse.event("done", map[string]any{
+		"count":       len(resp.Results),
+		"next_cursor": resp.NextCursor,
+		"latency_ms":  time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithDeadline_EndsStreamWithoutErrorResponse(t *testing.T) {
+	h := withDeadline(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sse, ok := newSSEWriter(w)
+		if !ok {
+			t.Fatal("recorder must support flushing")
+		}
+		_ = sse.event("token", map[string]any{"text": "hi"})
+		<-r.Context().Done()
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/answer/stream", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if body := rec.Body.String(); body != "event: token\ndata: {\"text\":\"hi\"}\n\n" {
+		t.Fatalf("the deadline must only end the stream, got %q", body)
+	}
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/rbac"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+)
+
+// ndjsonType is the content type of exports and import bodies.
+const ndjsonType = "application/x-ndjson"
+
+type exportRequest struct {
+	ProjectID string `json:"project_id"`
+	// ActiveOnly exports only the active, non-deleted version of each doc.
+	ActiveOnly bool `json:"active_only"`
+}
+
+// handleExport streams a project's points, with vectors, as NDJSON. The
+// route is exempt from the request timeout; the export stops when the client
+// goes away.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(s.cfg.Limits.MaxContentBytes))).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_json"})
+		return
+	}
+	if req.ProjectID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields", "detail": "project_id is required"})
+		return
+	}
+	if apiErr := s.authorize(r, req.ProjectID, rbac.RoleAdmin); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	ctx := r.Context()
+	info, err := s.store.CollectionInfo(ctx, s.collection)
+	if err != nil {
+		storeError("collection_info_failed", err).write(w)
+		return
+	}
+	f := qdrant.Filter{"must": []any{matchValue("project_id", req.ProjectID)}}
+	if req.ActiveOnly {
+		f = buildBaseFilter([]string{req.ProjectID})
+	}
+	hdr := store.ExportHeader{
+		ProjectID:  req.ProjectID,
+		Collection: s.collection,
+		VectorSize: info.VectorSize,
+		Distance:   info.Distance,
+		ActiveOnly: req.ActiveOnly,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	w.Header().Set("Content-Type", ndjsonType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", req.ProjectID+".ndjson"))
+	w.WriteHeader(http.StatusOK)
+	n, err := store.Export(ctx, s.store, s.collection, f, hdr, flushWriter{w})
+	if err != nil {
+		// The status is already sent; the missing end line marks the export
+		// as truncated.
+		log.Printf("export project=%s failed after %d points: %v (request %s)", req.ProjectID, n, err, requestID(r))
+		return
+	}
+	log.Printf("export project=%s points=%d active_only=%t (request %s)", req.ProjectID, n, req.ActiveOnly, requestID(r))
+}
+
+// flushWriter flushes after every page so large exports stream.
+type flushWriter struct{ http.ResponseWriter }
+
+func (f flushWriter) Flush() {
+	if fl, ok := f.ResponseWriter.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+// stagedImport is an import body spooled to a temp file by a first pass that
+// validated every line, so nothing is written for a stream that turns out to
+// be malformed, truncated or for another project.
+type stagedImport struct {
+	file   *os.File
+	header store.ExportHeader
+	points int
+	// active maps each doc_id to the doc_version the export marks active.
+	active map[string]string
+}
+
+// stageImport reads body to its end line, checking that every point belongs
+// to projectID and, unless the points will be re-embedded, that its vector
+// has the header's size.
+func stageImport(body io.Reader, projectID string, reembed bool) (*stagedImport, *apiError) {
+	f, err := os.CreateTemp("", "kbg-import-*.ndjson")
+	if err != nil {
+		log.Printf("import: staging: %v", err)
+		return nil, &apiError{Status: http.StatusInternalServerError, Code: "import_staging_failed"}
+	}
+	si := &stagedImport{file: f, active: map[string]string{}}
+	reject := func(code, detail string) (*stagedImport, *apiError) {
+		si.Close()
+		return nil, &apiError{Status: http.StatusBadRequest, Code: code, Detail: detail}
+	}
+	ir, err := store.NewImportReader(io.TeeReader(body, f))
+	if err != nil {
+		return reject("invalid_export", err.Error())
+	}
+	if ir.Header.ProjectID != projectID {
+		return reject("project_mismatch", fmt.Sprintf("export is for project %s", ir.Header.ProjectID))
+	}
+	si.header = ir.Header
+	for {
+		points, err := ir.Next(256)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return reject("invalid_export", err.Error())
+		}
+		for _, p := range points {
+			if p.Payload["project_id"] != projectID {
+				return reject("project_mismatch", fmt.Sprintf("point %v belongs to another project", p.ID))
+			}
+			if !reembed && len(p.Vector) != ir.Header.VectorSize {
+				return reject("invalid_export", fmt.Sprintf("point %v has a %d-dim vector, header says %d", p.ID, len(p.Vector), ir.Header.VectorSize))
+			}
+			if active, _ := p.Payload["is_active"].(bool); active {
+				doc, _ := p.Payload["doc_id"].(string)
+				if v, _ := p.Payload["doc_version"].(string); v > si.active[doc] {
+					si.active[doc] = v
+				}
+			}
+		}
+		si.points += len(points)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		si.Close()
+		log.Printf("import: staging: %v", err)
+		return nil, &apiError{Status: http.StatusInternalServerError, Code: "import_staging_failed"}
+	}
+	return si, nil
+}
+
+func (si *stagedImport) Close() {
+	si.file.Close()
+	os.Remove(si.file.Name())
+}
+
+// handleImport restores an export into the live collection or, with
+// ?collection= (admins of every project only), into another one, created if
+// missing. The body is validated in full before anything is written. Points
+// keep their IDs, so re-importing is idempotent; each doc's points are
+// written with their exported state and its active version is activated
+// under the doc lock, so restoring into the live collection never takes a
+// doc out of search. ?replace=true first deletes the project's points in
+// the target, holding every doc lock; ?reembed=true re-embeds them with the
+// current embedder instead of keeping the exported vectors.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	projectID := q.Get("project_id")
+	if projectID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "missing_fields", "detail": "project_id is required"})
+		return
+	}
+	reembed, _ := strconv.ParseBool(q.Get("reembed"))
+	replace, _ := strconv.ParseBool(q.Get("replace"))
+	if apiErr := s.authorize(r, projectID, rbac.RoleAdmin); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	collection := s.collection
+	if c := q.Get("collection"); c != "" && c != s.collection {
+		// Physical collections are shared by every project.
+		if apiErr := s.authorizeAdmin(r); apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+		collection = c
+	}
+	ctx := r.Context()
+	si, apiErr := stageImport(r.Body, projectID, reembed)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	defer si.Close()
+
+	dim := si.header.VectorSize
+	if reembed {
+		dim = s.embedder.Dim()
+	}
+	if collection != s.collection {
+		if err := s.store.EnsureCollection(ctx, collection, dim); err != nil {
+			storeError("ensure_collection_failed", err).write(w)
+			return
+		}
+		if err := s.createIndexes(ctx, collection); err != nil {
+			storeError("ensure_collection_failed", err).write(w)
+			return
+		}
+	}
+	info, err := s.store.CollectionInfo(ctx, collection)
+	if err != nil {
+		storeError("collection_info_failed", err).write(w)
+		return
+	}
+	if info.VectorSize != dim {
+		detail := fmt.Sprintf("collection %s has %d-dim vectors, import has %d; use reembed=true", collection, info.VectorSize, dim)
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "collection_mismatch", "detail": detail})
+		return
+	}
+	ir, err := store.NewImportReader(si.file)
+	if err != nil {
+		log.Printf("import: reading staged export: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "import_staging_failed"})
+		return
+	}
+
+	lockDoc := func(docID string) func() { return s.docLocks.Lock(projectID + ":" + docID) }
+	if replace {
+		// Replacing also drops docs the export does not name.
+		unlock := s.docLocks.LockAll()
+		defer unlock()
+		lockDoc = func(string) func() { return func() {} }
+		f := qdrant.Filter{"must": []any{matchValue("project_id", projectID)}}
+		if err := s.store.DeleteByFilter(ctx, collection, f); err != nil {
+			storeError("qdrant_delete_failed", err).write(w)
+			return
+		}
+	}
+
+	batching := store.BatchOptions{Size: s.cfg.Store.UpsertBatchSize, Parallelism: s.cfg.Store.UpsertParallelism}
+	imported := 0
+	for {
+		points, err := ir.Next(256)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Printf("import: reading staged export: %v", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "import_staging_failed", "imported": imported})
+			return
+		}
+		if reembed {
+			if err := embedPoints(ctx, s.embedder, points); err != nil {
+				writeJSON(w, http.StatusBadGateway, map[string]any{"error": "embed_failed", "imported": imported})
+				return
+			}
+		}
+		for _, batch := range groupByDoc(points) {
+			doc := batch.docID
+			unlock := lockDoc(doc)
+			err := s.importDoc(ctx, collection, projectID, doc, si.active[doc], batch.points, batching)
+			unlock()
+			if err != nil {
+				apiErr := storeError("qdrant_upsert_failed", err)
+				body := map[string]any{"error": apiErr.Code, "imported": imported, "doc_id": doc}
+				if apiErr.Detail != "" {
+					body["detail"] = apiErr.Detail
+				}
+				writeJSON(w, apiErr.Status, body)
+				return
+			}
+			imported += len(batch.points)
+		}
+	}
+	log.Printf("import project=%s collection=%s points=%d docs=%d reembed=%t replace=%t (request %s)", projectID, collection, imported, len(si.active), reembed, replace, requestID(r))
+	writeJSON(w, http.StatusOK, map[string]any{"project_id": projectID, "collection": collection, "imported": imported})
+}
+
+type docPoints struct {
+	docID  string
+	points []qdrant.Point
+}
+
+// groupByDoc splits points by doc_id, keeping the order docs first appear in.
+func groupByDoc(points []qdrant.Point) []docPoints {
+	var out []docPoints
+	index := map[string]int{}
+	for _, p := range points {
+		doc, _ := p.Payload["doc_id"].(string)
+		i, ok := index[doc]
+		if !ok {
+			i = len(out)
+			index[doc] = i
+			out = append(out, docPoints{docID: doc})
+		}
+		out[i].points = append(out[i].points, p)
+	}
+	return out
+}
+
+// importDoc writes one doc's points with the state the export gives them,
+// active only for activeVersion, then makes activeVersion the doc's only
+// active one. The caller holds the doc lock, so a live doc overwritten by its
+// own export stays searchable and no ingest interleaves.
+func (s *Server) importDoc(ctx context.Context, collection, projectID, docID, activeVersion string, points []qdrant.Point, batching store.BatchOptions) error {
+	for _, p := range points {
+		v, _ := p.Payload["doc_version"].(string)
+		p.Payload["is_active"] = activeVersion != "" && v == activeVersion
+	}
+	if err := store.UpsertWithRetry(ctx, s.store, collection, points, batching, s.cfg.Store.UpsertRetries); err != nil {
+		return err
+	}
+	if activeVersion == "" {
+		return nil
+	}
+	return s.activateIn(ctx, collection, projectID, docID, activeVersion)
+}
+
+// snapshotCollection is the physical collection behind the alias; Qdrant
+// snapshots name collections, not aliases.
+func (s *Server) snapshotCollection(r *http.Request) (string, *apiError) {
+	if as, ok := s.store.aliases(); ok && s.cfg.Qdrant.Alias != "" {
+		c, err := as.ResolveAlias(r.Context(), s.cfg.Qdrant.Alias)
+		if err != nil {
+			return "", storeError("resolve_alias_failed", err)
+		}
+		if c == "" {
+			return "", &apiError{Status: http.StatusServiceUnavailable, Code: "alias_missing"}
+		}
+		return c, nil
+	}
+	return s.collection, nil
+}
+
+func (s *Server) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	sn, ok := s.store.snapshots()
+	if !ok {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "snapshots_unsupported"})
+		return
+	}
+	collection, apiErr := s.snapshotCollection(r)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	info, err := sn.CreateSnapshot(r.Context(), collection)
+	if err != nil {
+		storeError("snapshot_failed", err).write(w)
+		return
+	}
+	log.Printf("snapshot %s of %s created (%d bytes)", info.Name, collection, info.Size)
+	writeJSON(w, http.StatusOK, map[string]any{"collection": collection, "snapshot": info})
+}
+
+func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if apiErr := s.authorizeAdmin(r); apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	sn, ok := s.store.snapshots()
+	if !ok {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": "snapshots_unsupported"})
+		return
+	}
+	collection, apiErr := s.snapshotCollection(r)
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	list, err := sn.ListSnapshots(r.Context(), collection)
+	if err != nil {
+		storeError("list_snapshots_failed", err).write(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"collection": collection, "snapshots": list})
+}
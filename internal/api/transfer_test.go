@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/apikey"
+	"github.com/HardMakabaka/KB-Gateway/internal/config"
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/HardMakabaka/KB-Gateway/internal/store"
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func exportProject(t *testing.T, h http.Handler, req exportRequest) string {
+	t.Helper()
+	b, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/admin/export", bytes.NewReader(b)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ndjsonType {
+		t.Fatalf("export: status %d %s", rec.Code, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+func importProject(t *testing.T, h http.Handler, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/v1/admin/import?"+query, bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", ndjsonType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestTransfer_ExportImportProject(t *testing.T) {
+	src, _ := newTestServer(t)
+	for i, content := range []string{"old handbook", "new handbook"} {
+		if i > 0 {
+			// doc_version has second resolution.
+			time.Sleep(1100 * time.Millisecond)
+		}
+		req := ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: content, ACLPublic: true}
+		if code := doJSON(t, src, "/v1/docs/ingest", req, nil); code != http.StatusOK {
+			t.Fatalf("ingest: status %d", code)
+		}
+	}
+	if code := doJSON(t, src, "/v1/docs/ingest", ingestRequest{ProjectID: "proj2", DocID: "doc9", Content: "other project", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest proj2: status %d", code)
+	}
+
+	all := exportProject(t, src, exportRequest{ProjectID: "proj1"})
+	active := exportProject(t, src, exportRequest{ProjectID: "proj1", ActiveOnly: true})
+	if got := strings.Count(all, `"type":"point"`); got != 2 {
+		t.Fatalf("expected both versions exported, got %d points:\n%s", got, all)
+	}
+	if got := strings.Count(active, `"type":"point"`); got != 1 || !strings.Contains(active, "new handbook") {
+		t.Fatalf("expected only the active version, got:\n%s", active)
+	}
+
+	dst, st := newTestServer(t)
+	if rec := importProject(t, dst, "project_id=proj2", all); rec.Code != http.StatusBadRequest {
+		t.Fatalf("importing into another project must fail, got %d", rec.Code)
+	}
+	if rec := importProject(t, dst, "project_id=proj1", all[:len(all)/2]); rec.Code != http.StatusBadRequest {
+		t.Fatalf("a truncated export must be rejected, got %d", rec.Code)
+	}
+	if rec := importProject(t, dst, "project_id=proj1&replace=true", all); rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d %s", rec.Code, rec.Body.String())
+	}
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	if res := searchAs(t, dst, internal, "handbook"); len(res) != 1 || res[0].Text != "new handbook" {
+		t.Fatalf("expected the active version after import, got %+v", res)
+	}
+
+	if rec := importProject(t, dst, "project_id=proj1&collection=restored&reembed=true", active); rec.Code != http.StatusOK {
+		t.Fatalf("import into a new collection: status %d %s", rec.Code, rec.Body.String())
+	}
+	if n, _ := st.Count(context.Background(), "restored", nil); n != 1 {
+		t.Fatalf("expected 1 point in the restored collection, got %d", n)
+	}
+}
+
+func TestTransfer_SnapshotsNeedQdrant(t *testing.T) {
+	h, _ := newTestServer(t)
+	if code := doJSON(t, h, "/v1/admin/snapshots", nil, nil); code != http.StatusConflict {
+		t.Fatalf("expected 409 on the memory backend, got %d", code)
+	}
+}
+
+func TestTransfer_ImportValidatesBeforeWriting(t *testing.T) {
+	h, st := newTestServer(t)
+	ctx := context.Background()
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "live handbook", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+	export := exportProject(t, h, exportRequest{ProjectID: "proj1"})
+	before, _ := st.Count(ctx, "kb", nil)
+
+	lines := strings.SplitAfter(strings.TrimSpace(export), "\n")
+	foreign := strings.Replace(lines[1], `"project_id":"proj1"`, `"project_id":"proj2"`, 1)
+	short := strings.Replace(lines[1], `"vector":[`, `"vector":[0.5,`, 1)
+	for name, body := range map[string]string{
+		"truncated":       strings.Join(lines[:len(lines)-1], ""),
+		"foreign point":   lines[0] + foreign + lines[len(lines)-1],
+		"malformed point": lines[0] + "{not json\n" + lines[len(lines)-1],
+		"vector size":     lines[0] + short + lines[len(lines)-1],
+	} {
+		if rec := importProject(t, h, "project_id=proj1&replace=true", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %s", name, rec.Code, rec.Body.String())
+		}
+		if n, _ := st.Count(ctx, "kb", nil); n != before {
+			t.Fatalf("%s: a rejected replace must leave the project alone, got %d points, want %d", name, n, before)
+		}
+	}
+}
+
+func TestTransfer_ImportKeepsOneActiveVersion(t *testing.T) {
+	h, _ := newTestServer(t)
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "exported handbook", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+	export := exportProject(t, h, exportRequest{ProjectID: "proj1"})
+	time.Sleep(1100 * time.Millisecond)
+	if code := doJSON(t, h, "/v1/docs/ingest", ingestRequest{ProjectID: "proj1", DocID: "doc1", Content: "newer handbook", ACLPublic: true}, nil); code != http.StatusOK {
+		t.Fatalf("ingest: status %d", code)
+	}
+
+	if rec := importProject(t, h, "project_id=proj1", export); rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d %s", rec.Code, rec.Body.String())
+	}
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	if res := searchAs(t, h, internal, "handbook"); len(res) != 1 || res[0].Text != "exported handbook" {
+		t.Fatalf("expected only the imported version active, got %+v", res)
+	}
+}
+
+func TestTransfer_CollectionNeedsAllProjectsAdmin(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Auth.APIKeysFile = filepath.Join(t.TempDir(), "keys.json")
+	keys, err := apikey.Open(cfg.Auth.APIKeysFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, projectKey, _ := keys.Issue("proj1", []apikey.Scope{apikey.ScopeAdmin, apikey.ScopeIngest}, "")
+	h := newServer(cfg, store.NewMemory())
+	waitHealth(t, h, "ok")
+
+	do := func(r *http.Request) *httptest.ResponseRecorder {
+		r.Header.Set("X-API-Key", projectKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	if rec := do(httptest.NewRequest(http.MethodPost, "/v1/docs/ingest", strings.NewReader(`{"project_id":"proj1","doc_id":"doc1","content":"x","acl_public":true}`))); rec.Code != http.StatusOK {
+		t.Fatalf("ingest: status %d %s", rec.Code, rec.Body.String())
+	}
+	rec := do(httptest.NewRequest(http.MethodPost, "/v1/admin/export", strings.NewReader(`{"project_id":"proj1"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d %s", rec.Code, rec.Body.String())
+	}
+	export := rec.Body.String()
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/admin/import?project_id=proj1&collection=other", strings.NewReader(export))
+	r.Header.Set("Content-Type", ndjsonType)
+	if rec := do(r); rec.Code != http.StatusForbidden {
+		t.Fatalf("a project key must not pick the collection, got %d %s", rec.Code, rec.Body.String())
+	}
+	r = httptest.NewRequest(http.MethodPost, "/v1/admin/import?project_id=proj1", strings.NewReader(export))
+	r.Header.Set("Content-Type", ndjsonType)
+	if rec := do(r); rec.Code != http.StatusOK {
+		t.Fatalf("a project key may import into the live collection, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// upsertHookStore runs afterUpsert once each upsert has been written.
+type upsertHookStore struct {
+	*store.Memory
+	afterUpsert func()
+}
+
+func (s *upsertHookStore) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	if err := s.Memory.Upsert(ctx, collection, points); err != nil {
+		return err
+	}
+	if s.afterUpsert != nil {
+		s.afterUpsert()
+	}
+	return nil
+}
+
+func TestTransfer_ReimportKeepsDocsSearchable(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &upsertHookStore{Memory: store.NewMemory()}
+	if err := st.EnsureCollection(context.Background(), cfg.Qdrant.Collection, 384); err != nil {
+		t.Fatal(err)
+	}
+	h := newServer(cfg, st)
+	waitHealth(t, h, "ok")
+	docs := []string{"doc1", "doc2", "doc3"}
+	for _, doc := range docs {
+		req := ingestRequest{ProjectID: "proj1", DocID: doc, Content: "handbook for " + doc, ACLPublic: true}
+		if code := doJSON(t, h, "/v1/docs/ingest", req, nil); code != http.StatusOK {
+			t.Fatalf("ingest %s: status %d", doc, code)
+		}
+	}
+	export := exportProject(t, h, exportRequest{ProjectID: "proj1"})
+
+	internal := types.Principal{Type: types.PrincipalInternalUser, ID: "u1"}
+	upserts := 0
+	st.afterUpsert = func() {
+		upserts++
+		if res := searchAs(t, h, internal, "handbook"); len(res) != len(docs) {
+			t.Errorf("after import upsert %d: expected %d docs searchable, got %+v", upserts, len(docs), res)
+		}
+	}
+	if rec := importProject(t, h, "project_id=proj1", export); rec.Code != http.StatusOK {
+		t.Fatalf("import: status %d %s", rec.Code, rec.Body.String())
+	}
+	if upserts == 0 {
+		t.Fatal("import wrote nothing")
+	}
+	if res := searchAs(t, h, internal, "handbook"); len(res) != len(docs) {
+		t.Fatalf("expected %d docs after import, got %+v", len(docs), res)
+	}
+}
@@ -0,0 +1,314 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeIngest   Scope = "ingest"
+	ScopeActivate Scope = "activate"
+	ScopeDelete   Scope = "delete"
+	ScopeSearch   Scope = "search"
+	// ScopeImpersonate lets a key pass an end-user principal in the request body.
+	ScopeImpersonate Scope = "impersonate"
+	// ScopeAdmin allows exporting and importing the key's project, changing
+	// its document ACLs and, for AllProjects keys, collection-wide operations
+	// such as migrations.
+	ScopeAdmin Scope = "admin"
+)
+
+// AllProjects as a key's project grants access to every project.
+const AllProjects = "*"
+
+var knownScopes = map[Scope]bool{
+	ScopeIngest:      true,
+	ScopeActivate:    true,
+	ScopeDelete:      true,
+	ScopeSearch:      true,
+	ScopeImpersonate: true,
+	ScopeAdmin:       true,
+}
+
+// KeyPrefix starts every issued key, so keys are recognisable in headers and logs.
+const KeyPrefix = "kbg_"
+
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrRevoked    = errors.New("api key revoked")
+	ErrNotFound   = errors.New("api key not found")
+)
+
+// Key is a stored API key. Only the SHA-256 of the secret is kept; the
+// plaintext is shown once when the key is issued.
+type Key struct {
+	ID          string     `json:"id"`
+	Hash        string     `json:"hash"`
+	ProjectID   string     `json:"project_id"`
+	Scopes      []Scope    `json:"scopes"`
+	Description string     `json:"description,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k Key) HasScope(s Scope) bool {
+	for _, x := range k.Scopes {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (k Key) CoversProject(projectID string) bool {
+	return k.ProjectID == AllProjects || k.ProjectID == projectID
+}
+
+func ParseScopes(s string) ([]Scope, error) {
+	var out []Scope
+	for _, part := range strings.Split(s, ",") {
+		sc := Scope(strings.TrimSpace(part))
+		if sc == "" {
+			continue
+		}
+		if !knownScopes[sc] {
+			return nil, fmt.Errorf("unknown scope %q", sc)
+		}
+		out = append(out, sc)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no scopes given")
+	}
+	return out, nil
+}
+
+// Store is a JSON file of hashed keys. The gateway only reads it and picks up
+// changes made by the admin CLI (issue/revoke) when the file's mtime changes.
+type Store struct {
+	path string
+
+	mu        sync.Mutex
+	keys      map[string]Key
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// reloadInterval bounds how often Authenticate stats the file.
+const reloadInterval = 2 * time.Second
+
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string]Key{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type file struct {
+	Keys []Key `json:"keys"`
+}
+
+func (s *Store) load() error {
+	st, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.keys = map[string]Key{}
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	keys := make(map[string]Key, len(f.Keys))
+	for _, k := range f.Keys {
+		keys[k.ID] = k
+	}
+	s.keys = keys
+	s.modTime = st.ModTime()
+	return nil
+}
+
+func (s *Store) maybeReload() {
+	if time.Since(s.checkedAt) < reloadInterval {
+		return
+	}
+	s.checkedAt = time.Now()
+	st, err := os.Stat(s.path)
+	if err == nil && st.ModTime().Equal(s.modTime) {
+		return
+	}
+	// Keep serving the previous key set if the file is briefly unreadable.
+	_ = s.load()
+}
+
+// Authenticate resolves a plaintext key of the form kbg_<id>_<secret>.
+func (s *Store) Authenticate(plaintext string) (Key, error) {
+	id, secret, ok := splitKey(plaintext)
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+	s.mu.Lock()
+	s.maybeReload()
+	k, found := s.keys[id]
+	s.mu.Unlock()
+	if !found || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.Hash)) != 1 {
+		return Key{}, ErrInvalidKey
+	}
+	if k.RevokedAt != nil {
+		return Key{}, ErrRevoked
+	}
+	return k, nil
+}
+
+// Issue creates a key, persists its hash and returns the plaintext once.
+func (s *Store) Issue(projectID string, scopes []Scope, description string) (Key, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return Key{}, "", err
+	}
+	idBytes, err := randomBytes(6)
+	if err != nil {
+		return Key{}, "", err
+	}
+	secretBytes, err := randomBytes(32)
+	if err != nil {
+		return Key{}, "", err
+	}
+	id := hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	k := Key{
+		ID:          id,
+		Hash:        hashSecret(secret),
+		ProjectID:   projectID,
+		Scopes:      scopes,
+		Description: description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	s.keys[id] = k
+	if err := s.save(); err != nil {
+		return Key{}, "", err
+	}
+	return k, KeyPrefix + id + "_" + secret, nil
+}
+
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if k.RevokedAt == nil {
+		now := time.Now().UTC()
+		k.RevokedAt = &now
+		s.keys[id] = k
+	}
+	return s.save()
+}
+
+func (s *Store) List() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.load()
+	out := make([]Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		out = append(out, k)
+	}
+	return out
+}
+
+func (s *Store) save() error {
+	f := file{Keys: make([]Key, 0, len(s.keys))}
+	for _, k := range s.keys {
+		f.Keys = append(f.Keys, k)
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	st, err := os.Stat(s.path)
+	if err == nil {
+		s.modTime = st.ModTime()
+	}
+	return nil
+}
+
+func splitKey(plaintext string) (id, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(plaintext, KeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type contextKey int
+
+const keyContextKey contextKey = iota
+
+func WithKey(ctx context.Context, k Key) context.Context {
+	return context.WithValue(ctx, keyContextKey, k)
+}
+
+// FromContext returns the API key the request authenticated with, if any.
+func FromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(keyContextKey).(Key)
+	return k, ok
+}
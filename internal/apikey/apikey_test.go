@@ -0,0 +1,63 @@
+package apikey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIssueAuthenticateRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, plaintext, err := s.Issue("proj1", []Scope{ScopeIngest, ScopeSearch}, "feeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plaintext, KeyPrefix+k.ID+"_") {
+		t.Fatalf("unexpected key format %q", plaintext)
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), strings.TrimPrefix(plaintext, KeyPrefix+k.ID+"_")) {
+		t.Fatalf("secret must not be stored in plaintext")
+	}
+
+	got, err := s.Authenticate(plaintext)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if !got.HasScope(ScopeIngest) || got.HasScope(ScopeDelete) || !got.CoversProject("proj1") || got.CoversProject("proj2") {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+	if _, err := s.Authenticate(plaintext + "x"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected invalid key, got %v", err)
+	}
+
+	// Revocation through a second handle (as the admin CLI does) is picked up on reload.
+	admin, _ := Open(path)
+	if err := admin.Revoke(k.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.checkedAt = s.checkedAt.Add(-reloadInterval)
+	s.modTime = s.modTime.Add(-1)
+	if _, err := s.Authenticate(plaintext); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("expected revoked, got %v", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	sc, err := ParseScopes("ingest, activate")
+	if err != nil || len(sc) != 2 {
+		t.Fatalf("unexpected: %v %v", sc, err)
+	}
+	if _, err := ParseScopes("ingest,root"); err == nil {
+		t.Fatalf("expected unknown scope to be rejected")
+	}
+	if _, err := ParseScopes(""); err == nil {
+		t.Fatalf("expected empty scopes to be rejected")
+	}
+}
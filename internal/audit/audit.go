@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is one audit record. Before/After hold the changed payload fields.
+type Event struct {
+	Time       time.Time      `json:"time"`
+	Action     string         `json:"action"`
+	ActorType  string         `json:"actor_type"`
+	ActorID    string         `json:"actor_id"`
+	ProjectID  string         `json:"project_id,omitempty"`
+	DocID      string         `json:"doc_id,omitempty"`
+	DocVersion string         `json:"doc_version,omitempty"`
+	Before     map[string]any `json:"before,omitempty"`
+	After      map[string]any `json:"after,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+}
+
+// Logger appends events as JSON lines. v1 keeps audit in a local file (or
+// the process log); see docs/DESIGN.md.
+type Logger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Open appends to path, or writes to the process log when path is empty.
+func Open(path string) (*Logger, error) {
+	if path == "" {
+		return &Logger{}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{w: f}, nil
+}
+
+// NewWriter logs to w; used by tests.
+func NewWriter(w io.Writer) *Logger { return &Logger{w: w} }
+
+func (l *Logger) Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("audit: marshal event %s: %v", e.Action, err)
+		return
+	}
+	if l == nil || l.w == nil {
+		log.Printf("audit: %s", b)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		log.Printf("audit: write failed (%v): %s", err, b)
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+func WithPrincipal(ctx context.Context, p types.Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// PrincipalFrom returns the authenticated principal stored by the middleware.
+func PrincipalFrom(ctx context.Context) (types.Principal, bool) {
+	p, ok := ctx.Value(principalKey).(types.Principal)
+	return p, ok
+}
+
+// ValidType reports whether t is one of the known principal types.
+func ValidType(t types.PrincipalType) bool {
+	switch t {
+	case types.PrincipalInternalUser, types.PrincipalCustomerUser, types.PrincipalService:
+		return true
+	}
+	return false
+}
+
+// Authenticator turns bearer JWTs into principals.
+type Authenticator struct {
+	verifier      *Verifier
+	typeClaim     string
+	groupsClaim   string
+	impersonators map[string]bool
+}
+
+// NewAuthenticator derives principals from sub, typeClaim and groupsClaim.
+// Only service principals whose ID is in impersonators may act on behalf of
+// a principal supplied in the request body.
+func NewAuthenticator(v *Verifier, typeClaim, groupsClaim string, impersonators []string) *Authenticator {
+	a := &Authenticator{verifier: v, typeClaim: typeClaim, groupsClaim: groupsClaim, impersonators: map[string]bool{}}
+	for _, id := range impersonators {
+		if id = strings.TrimSpace(id); id != "" {
+			a.impersonators[id] = true
+		}
+	}
+	return a
+}
+
+func (a *Authenticator) PrincipalFromClaims(c Claims) (types.Principal, error) {
+	sub, _ := c["sub"].(string)
+	if sub == "" {
+		return types.Principal{}, errors.New("missing sub claim")
+	}
+	typ, _ := c[a.typeClaim].(string)
+	pt := types.PrincipalType(typ)
+	if !ValidType(pt) {
+		return types.Principal{}, fmt.Errorf("invalid %s claim %q", a.typeClaim, typ)
+	}
+	var groups []string
+	switch g := c[a.groupsClaim].(type) {
+	case nil:
+	case []any:
+		for _, x := range g {
+			s, ok := x.(string)
+			if !ok {
+				return types.Principal{}, fmt.Errorf("invalid %s claim", a.groupsClaim)
+			}
+			groups = append(groups, s)
+		}
+	default:
+		return types.Principal{}, fmt.Errorf("invalid %s claim", a.groupsClaim)
+	}
+	return types.Principal{Type: pt, ID: sub, Groups: groups}, nil
+}
+
+func (a *Authenticator) CanImpersonate(p types.Principal) bool {
+	return p.Type == types.PrincipalService && a.impersonators[p.ID]
+}
+
+// Middleware rejects requests without a valid bearer token and stores the
+// derived principal in the request context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, "missing_token")
+			return
+		}
+		claims, err := a.verifier.Verify(token)
+		if err != nil {
+			log.Printf("auth: rejected token: %v", err)
+			unauthorized(w, "invalid_token")
+			return
+		}
+		p, err := a.PrincipalFromClaims(claims)
+		if err != nil {
+			log.Printf("auth: rejected token: %v", err)
+			unauthorized(w, "invalid_token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, code string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="kb-gateway"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": code})
+}
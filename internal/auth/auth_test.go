@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func b64(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signHS256(secret string, hdr, claims map[string]any) string {
+	in := b64(hdr) + "." + b64(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(in))
+	return in + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	in := b64(map[string]any{"alg": "RS256", "kid": kid}) + "." + b64(claims)
+	digest := sha256.Sum256([]byte(in))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return in + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":            "u1",
+		"principal_type": "internal_user",
+		"groups":         []string{"eng"},
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iss":            "idp",
+		"aud":            []string{"kb-gateway"},
+	}
+}
+
+func TestVerify_HS256(t *testing.T) {
+	v, err := NewVerifier(VerifierConfig{HS256Secret: "s3cret", Issuer: "idp", Audience: "kb-gateway"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := map[string]any{"alg": "HS256"}
+	if _, err := v.Verify(signHS256("s3cret", hdr, validClaims())); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if _, err := v.Verify(signHS256("other", hdr, validClaims())); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected bad signature, got %v", err)
+	}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	if _, err := v.Verify(signHS256("s3cret", hdr, expired)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected expired, got %v", err)
+	}
+	wrongAud := validClaims()
+	wrongAud["aud"] = "someone-else"
+	if _, err := v.Verify(signHS256("s3cret", hdr, wrongAud)); !errors.Is(err, ErrBadAudience) {
+		t.Fatalf("expected bad audience, got %v", err)
+	}
+
+	none := b64(map[string]any{"alg": "none"}) + "." + b64(validClaims()) + "."
+	if _, err := v.Verify(none); !errors.Is(err, ErrUnsupportedAlg) {
+		t.Fatalf("expected alg none to be rejected, got %v", err)
+	}
+}
+
+func TestVerify_RS256FromJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := map[string]any{"keys": []map[string]any{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := json.Marshal(jwks)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(VerifierConfig{JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(signRS256(key, "k1", validClaims())); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if _, err := v.Verify(signRS256(key, "k2", validClaims())); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected unknown key, got %v", err)
+	}
+	// An RS256-only verifier must not accept HS256 tokens, whatever the secret.
+	if _, err := v.Verify(signHS256("x", map[string]any{"alg": "HS256"}, validClaims())); !errors.Is(err, ErrUnsupportedAlg) {
+		t.Fatalf("expected HS256 to be rejected, got %v", err)
+	}
+}
+
+func TestMiddleware_DerivesPrincipal(t *testing.T) {
+	v, _ := NewVerifier(VerifierConfig{HS256Secret: "s3cret"})
+	a := NewAuthenticator(v, "principal_type", "groups", []string{"svc-bot"})
+
+	var got types.Principal
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = PrincipalFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/search", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256("s3cret", map[string]any{"alg": "HS256"}, validClaims()))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got.ID != "u1" || got.Type != types.PrincipalInternalUser || len(got.Groups) != 1 || got.Groups[0] != "eng" {
+		t.Fatalf("unexpected principal: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/search", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	bad := validClaims()
+	bad["principal_type"] = "root"
+	req = httptest.NewRequest(http.MethodPost, "/v1/search", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256("s3cret", map[string]any{"alg": "HS256"}, bad))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown principal type, got %d", rec.Code)
+	}
+}
+
+func TestCanImpersonate(t *testing.T) {
+	a := NewAuthenticator(nil, "principal_type", "groups", []string{"svc-bot"})
+	if !a.CanImpersonate(types.Principal{Type: types.PrincipalService, ID: "svc-bot"}) {
+		t.Fatalf("expected listed service to impersonate")
+	}
+	if a.CanImpersonate(types.Principal{Type: types.PrincipalInternalUser, ID: "svc-bot"}) {
+		t.Fatalf("only service principals may impersonate")
+	}
+	if a.CanImpersonate(types.Principal{Type: types.PrincipalService, ID: "other"}) {
+		t.Fatalf("unlisted service must not impersonate")
+	}
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken = errors.New("malformed token")
+	ErrUnsupportedAlg = errors.New("unsupported or unconfigured signing algorithm")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrBadSignature   = errors.New("invalid token signature")
+	ErrExpired        = errors.New("token expired")
+	ErrNotYetValid    = errors.New("token not yet valid")
+	ErrBadIssuer      = errors.New("unexpected token issuer")
+	ErrBadAudience    = errors.New("unexpected token audience")
+)
+
+// Claims is the decoded JWT payload.
+type Claims map[string]any
+
+// VerifierConfig selects the accepted keys and claim checks. HS256 is enabled
+// by HS256Secret; RS256 by PublicKeyFile (PEM) and/or JWKSFile.
+type VerifierConfig struct {
+	HS256Secret   string
+	PublicKeyFile string
+	JWKSFile      string
+	Issuer        string
+	Audience      string
+	Leeway        time.Duration
+}
+
+// Verifier validates compact JWS tokens signed with HS256 or RS256. The
+// algorithm must match the kind of key configured, so an RSA public key can
+// never be used as an HMAC secret.
+type Verifier struct {
+	hmacKey  []byte
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	v := &Verifier{
+		rsaKeys:  map[string]*rsa.PublicKey{},
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+		now:      time.Now,
+	}
+	if cfg.HS256Secret != "" {
+		v.hmacKey = []byte(cfg.HS256Secret)
+	}
+	if cfg.PublicKeyFile != "" {
+		key, err := loadPEMPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys[""] = key
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		for kid, k := range keys {
+			v.rsaKeys[kid] = k
+		}
+	}
+	if v.hmacKey == nil && len(v.rsaKeys) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+	return v, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var hdr jwtHeader
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, ErrMalformedToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch hdr.Alg {
+	case "HS256":
+		if v.hmacKey == nil {
+			return nil, ErrUnsupportedAlg
+		}
+		mac := hmac.New(sha256.New, v.hmacKey)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, ErrBadSignature
+		}
+	case "RS256":
+		key, err := v.rsaKey(hdr.Kid)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, ErrBadSignature
+		}
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) rsaKey(kid string) (*rsa.PublicKey, error) {
+	if k, ok := v.rsaKeys[kid]; ok {
+		return k, nil
+	}
+	// A token without kid may use the only configured key.
+	if kid == "" && len(v.rsaKeys) == 1 {
+		for _, k := range v.rsaKeys {
+			return k, nil
+		}
+	}
+	if len(v.rsaKeys) == 0 {
+		return nil, ErrUnsupportedAlg
+	}
+	return nil, ErrUnknownKey
+}
+
+func (v *Verifier) checkClaims(c Claims) error {
+	now := v.now()
+	exp, ok := c["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing exp", ErrMalformedToken)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return ErrExpired
+	}
+	if nbf, ok := c["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return ErrNotYetValid
+	}
+	if v.issuer != "" && c["iss"] != v.issuer {
+		return ErrBadIssuer
+	}
+	if v.audience != "" && !hasAudience(c["aud"], v.audience) {
+		return ErrBadAudience
+	}
+	return nil
+}
+
+func hasAudience(aud any, want string) bool {
+	switch x := aud.(type) {
+	case string:
+		return x == want
+	case []any:
+		for _, a := range x {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, out any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func loadPEMPublicKey(path string) (*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rk, ok := k.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an RSA public key", path)
+		}
+		return rk, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	out := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %s: bad modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwks key %s: bad exponent", k.Kid)
+		}
+		out[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s: no RSA signing keys", path)
+	}
+	return out, nil
+}
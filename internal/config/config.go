@@ -7,29 +7,123 @@ import (
 )
 
 type Config struct {
-	HTTP   HTTPConfig
-	Qdrant QdrantConfig
-	Embed  EmbedConfig
-	Chunk  ChunkConfig
-	Limits LimitsConfig
+	HTTP     HTTPConfig
+	Qdrant   QdrantConfig
+	Store    StoreConfig
+	Embed    EmbedConfig
+	Chunk    ChunkConfig
+	Limits   LimitsConfig
+	Metadata MetadataConfig
+	LLM      LLMConfig
+	Auth     AuthConfig
+	Audit    AuditConfig
+	Groups   GroupsConfig
+	Redact   RedactConfig
 }
 
 type HTTPConfig struct {
 	Addr string `envconfig:"HTTP_ADDR" default:":8080"`
+	// StreamTimeout bounds the SSE endpoints, which are exempt from the 30s
+	// request timeout.
+	StreamTimeout time.Duration `envconfig:"HTTP_STREAM_TIMEOUT" default:"5m"`
 }
 
 type QdrantConfig struct {
 	URL        string        `envconfig:"QDRANT_URL" default:"http://localhost:6333"`
 	Collection string        `envconfig:"QDRANT_COLLECTION" default:"kb_chunks"`
 	Timeout    time.Duration `envconfig:"QDRANT_TIMEOUT" default:"10s"`
+	// Alias is the name the gateway reads and writes through. It is created
+	// pointing at Collection if missing; migrations and collection switches
+	// repoint it. Empty, or a backend without aliases, uses Collection directly.
+	Alias string `envconfig:"QDRANT_ALIAS" default:"kb"`
+	// Transport is "http" (REST on URL) or "grpc" (GRPCAddr).
+	Transport string `envconfig:"QDRANT_TRANSPORT" default:"http"`
+	GRPCAddr  string `envconfig:"QDRANT_GRPC_ADDR" default:"localhost:6334"`
+	// Unavailable errors (connection failures, 429/502/503/504) are retried
+	// with jittered exponential backoff; BreakerThreshold consecutive ones
+	// fast-fail further calls for BreakerCooldown.
+	MaxRetries       int           `envconfig:"QDRANT_MAX_RETRIES" default:"3"`
+	RetryBackoff     time.Duration `envconfig:"QDRANT_RETRY_BACKOFF" default:"100ms"`
+	RetryMaxBackoff  time.Duration `envconfig:"QDRANT_RETRY_MAX_BACKOFF" default:"2s"`
+	BreakerThreshold int           `envconfig:"QDRANT_BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `envconfig:"QDRANT_BREAKER_COOLDOWN" default:"10s"`
+}
+
+// StoreConfig selects the vector store. Every backend names its collection
+// (or table) after KBG_QDRANT_COLLECTION.
+type StoreConfig struct {
+	// Backend is qdrant, postgres, embedded or memory.
+	Backend     string `envconfig:"STORE_BACKEND" default:"qdrant"`
+	PostgresDSN string `envconfig:"POSTGRES_DSN" default:""`
+	// EmbeddedPath is the bbolt file used by the embedded backend.
+	EmbeddedPath string `envconfig:"EMBEDDED_PATH" default:"kbg.db"`
+	// Ingest upserts are split into batches sent in parallel; batches that
+	// failed transiently are re-sent up to UpsertRetries times.
+	UpsertBatchSize   int `envconfig:"UPSERT_BATCH_SIZE" default:"64"`
+	UpsertParallelism int `envconfig:"UPSERT_PARALLELISM" default:"4"`
+	UpsertRetries     int `envconfig:"UPSERT_RETRIES" default:"2"`
 }
 
 type EmbedConfig struct {
 	Provider string `envconfig:"EMBED_PROVIDER" default:"openai"`
 	Model    string `envconfig:"EMBED_MODEL" default:"text-embedding-3-small"`
+	Dim      int    `envconfig:"EMBED_DIM" default:"384"`
 	APIKey   string `envconfig:"OPENAI_API_KEY" default:""`
 }
 
+// LLMConfig configures the OpenAI-compatible chat completion endpoint used by /v1/answer.
+type LLMConfig struct {
+	BaseURL         string        `envconfig:"LLM_BASE_URL" default:"https://api.openai.com/v1"`
+	Model           string        `envconfig:"LLM_MODEL" default:"gpt-4o-mini"`
+	APIKey          string        `envconfig:"LLM_API_KEY" default:""`
+	Timeout         time.Duration `envconfig:"LLM_TIMEOUT" default:"30s"`
+	MaxContextChars int           `envconfig:"LLM_MAX_CONTEXT_CHARS" default:"12000"`
+}
+
+// AuthConfig enables bearer JWT authentication when any key is configured.
+type AuthConfig struct {
+	JWTHS256Secret     string        `envconfig:"AUTH_JWT_HS256_SECRET" default:""`
+	JWTPublicKeyFile   string        `envconfig:"AUTH_JWT_PUBLIC_KEY_FILE" default:""`
+	JWKSFile           string        `envconfig:"AUTH_JWKS_FILE" default:""`
+	Issuer             string        `envconfig:"AUTH_JWT_ISSUER" default:""`
+	Audience           string        `envconfig:"AUTH_JWT_AUDIENCE" default:""`
+	Leeway             time.Duration `envconfig:"AUTH_JWT_LEEWAY" default:"30s"`
+	PrincipalTypeClaim string        `envconfig:"AUTH_PRINCIPAL_TYPE_CLAIM" default:"principal_type"`
+	GroupsClaim        string        `envconfig:"AUTH_GROUPS_CLAIM" default:"groups"`
+	// Impersonators lists service principal IDs allowed to pass a body principal.
+	Impersonators []string `envconfig:"AUTH_IMPERSONATORS" default:""`
+	// APIKeysFile is the hashed API key store managed by cmd/kbg-admin.
+	APIKeysFile string `envconfig:"AUTH_API_KEYS_FILE" default:""`
+	// PolicyFile maps principal groups to per-project roles; see internal/rbac.
+	PolicyFile           string        `envconfig:"AUTH_POLICY_FILE" default:""`
+	PolicyReloadInterval time.Duration `envconfig:"AUTH_POLICY_RELOAD_INTERVAL" default:"10s"`
+}
+
+func (c AuthConfig) Enabled() bool {
+	return c.JWTHS256Secret != "" || c.JWTPublicKeyFile != "" || c.JWKSFile != ""
+}
+
+type AuditConfig struct {
+	// File receives audit events as JSON lines; empty logs them to stderr.
+	File string `envconfig:"AUDIT_FILE" default:""`
+}
+
+// GroupsConfig configures group expansion; see internal/groups.
+type GroupsConfig struct {
+	// File is a JSON group directory; LDIFFile an LDIF export standing in for LDAP.
+	File         string        `envconfig:"GROUPS_FILE" default:""`
+	LDIFFile     string        `envconfig:"GROUPS_LDIF_FILE" default:""`
+	SyncInterval time.Duration `envconfig:"GROUPS_SYNC_INTERVAL" default:"60s"`
+	CacheTTL     time.Duration `envconfig:"GROUPS_CACHE_TTL" default:"5m"`
+	// Hierarchy expands customer:acme:support to customer:acme.
+	Hierarchy bool `envconfig:"GROUPS_HIERARCHY" default:"true"`
+}
+
+type RedactConfig struct {
+	// RulesFile holds {"rules": [...]} regex rules; empty uses redact.DefaultRules.
+	RulesFile string `envconfig:"REDACT_RULES_FILE" default:""`
+}
+
 type ChunkConfig struct {
 	MaxChars  int `envconfig:"CHUNK_MAX_CHARS" default:"1200"`
 	Overlap   int `envconfig:"CHUNK_OVERLAP" default:"200"`
@@ -41,6 +135,11 @@ type LimitsConfig struct {
 	MaxContentBytes int `envconfig:"MAX_CONTENT_BYTES" default:"5242880"`
 }
 
+type MetadataConfig struct {
+	// SchemaFile is a JSON file with per-project metadata schemas; see internal/metadata.
+	SchemaFile string `envconfig:"METADATA_SCHEMA_FILE" default:""`
+}
+
 func Load() (Config, error) {
 	var cfg Config
 	if err := envconfig.Process("KBG", &cfg); err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"sync/atomic"
 )
 
 type Embedder interface {
@@ -33,3 +34,25 @@ func (f *FakeEmbedder) Embed(ctx context.Context, inputs []string) ([][]float32,
 	}
 	return out, nil
 }
+
+// Swappable forwards to an embedder that can be replaced at runtime, e.g.
+// when a re-embedding migration switches models.
+type Swappable struct {
+	cur atomic.Pointer[Embedder]
+}
+
+func NewSwappable(e Embedder) *Swappable {
+	s := &Swappable{}
+	s.Swap(e)
+	return s
+}
+
+func (s *Swappable) Swap(e Embedder) { s.cur.Store(&e) }
+
+func (s *Swappable) Current() Embedder { return *s.cur.Load() }
+
+func (s *Swappable) Dim() int { return s.Current().Dim() }
+
+func (s *Swappable) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	return s.Current().Embed(ctx, inputs)
+}
@@ -0,0 +1,270 @@
+package groups
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// Expansion sources, reported by Explain.
+const (
+	SourceDirect    = "direct"    // group carried by the principal itself
+	SourceMember    = "member"    // principal listed as a member in the directory
+	SourceDirectory = "directory" // parent group of another effective group
+	SourceHierarchy = "hierarchy" // colon prefix, e.g. customer:acme for customer:acme:support
+)
+
+// maxGroups caps an expanded group set; it ends up in a Qdrant match-any.
+const maxGroups = 1024
+
+// Directory describes group nesting and (optionally) principal membership.
+type Directory struct {
+	// Parents maps a group to the groups it is a member of.
+	Parents map[string][]string `json:"groups"`
+	// Members maps "<type>:<id>" (see MemberKey) to a principal's groups, for
+	// principals whose identity provider does not send them. Keying by type
+	// keeps a customer or service from inheriting the groups of an internal
+	// user that happens to share its ID.
+	Members map[string][]string `json:"members"`
+}
+
+// MemberKey is the Members key of the principal typ/id.
+func MemberKey(typ types.PrincipalType, id string) string {
+	return string(typ) + ":" + id
+}
+
+// validate rejects member keys that do not name a principal type.
+func (d *Directory) validate() error {
+	for key := range d.Members {
+		typ, id, ok := strings.Cut(key, ":")
+		switch types.PrincipalType(typ) {
+		case types.PrincipalInternalUser, types.PrincipalCustomerUser, types.PrincipalService:
+		default:
+			ok = false
+		}
+		if !ok || id == "" {
+			return fmt.Errorf("member %q: want <type>:<id>", key)
+		}
+	}
+	return nil
+}
+
+func (d *Directory) merge(o *Directory) {
+	if d.Parents == nil {
+		d.Parents = map[string][]string{}
+	}
+	if d.Members == nil {
+		d.Members = map[string][]string{}
+	}
+	for g, ps := range o.Parents {
+		d.Parents[g] = appendUnique(d.Parents[g], ps...)
+	}
+	for id, gs := range o.Members {
+		d.Members[id] = appendUnique(d.Members[id], gs...)
+	}
+}
+
+// Expansion explains why a group is in a principal's effective set.
+type Expansion struct {
+	Group  string `json:"group"`
+	Source string `json:"source"`
+	Via    string `json:"via,omitempty"`
+}
+
+// expand computes the transitive closure of a principal's groups.
+func (d *Directory) expand(pr types.Principal, direct []string, hierarchy bool) []Expansion {
+	seen := map[string]bool{}
+	var out []Expansion
+	var queue []string
+	add := func(g, source, via string) {
+		if g == "" || seen[g] || len(out) >= maxGroups {
+			return
+		}
+		seen[g] = true
+		out = append(out, Expansion{Group: g, Source: source, Via: via})
+		queue = append(queue, g)
+	}
+	for _, g := range direct {
+		add(g, SourceDirect, "")
+	}
+	if pr.ID != "" {
+		for _, g := range d.Members[MemberKey(pr.Type, pr.ID)] {
+			add(g, SourceMember, "")
+		}
+	}
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+		for _, p := range d.Parents[g] {
+			add(p, SourceDirectory, g)
+		}
+		if hierarchy {
+			if p, ok := colonParent(g); ok {
+				add(p, SourceHierarchy, g)
+			}
+		}
+	}
+	return out
+}
+
+// colonParent returns the parent of a colon-separated group name with at
+// least three segments: customer:acme:support -> customer:acme. Two-segment
+// names (customer:acme) never expand to a bare type like "customer".
+func colonParent(g string) (string, bool) {
+	i := strings.LastIndex(g, ":")
+	if i <= 0 || strings.Count(g, ":") < 2 {
+		return "", false
+	}
+	return g[:i], true
+}
+
+type Config struct {
+	File      string
+	LDIFFile  string
+	Hierarchy bool
+	CacheTTL  time.Duration
+}
+
+// Resolver expands principals' groups using a directory assembled from a
+// JSON file and/or an LDIF export, caching results per principal.
+type Resolver struct {
+	cfg Config
+
+	mu    sync.RWMutex
+	dir   *Directory
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	groups  []string
+	expires time.Time
+}
+
+const maxCacheEntries = 10000
+
+func NewResolver(cfg Config) (*Resolver, error) {
+	r := &Resolver{cfg: cfg, dir: &Directory{}, cache: map[string]cacheEntry{}}
+	if err := r.Sync(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Sync reloads the directory sources and drops the cache. On error the
+// previous directory stays active.
+func (r *Resolver) Sync() error {
+	dir := &Directory{}
+	if r.cfg.File != "" {
+		b, err := os.ReadFile(r.cfg.File)
+		if err != nil {
+			return err
+		}
+		var d Directory
+		if err := json.Unmarshal(b, &d); err != nil {
+			return fmt.Errorf("parse %s: %w", r.cfg.File, err)
+		}
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("parse %s: %w", r.cfg.File, err)
+		}
+		dir.merge(&d)
+	}
+	if r.cfg.LDIFFile != "" {
+		f, err := os.Open(r.cfg.LDIFFile)
+		if err != nil {
+			return err
+		}
+		d, err := ParseLDIF(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", r.cfg.LDIFFile, err)
+		}
+		dir.merge(d)
+	}
+	r.mu.Lock()
+	r.dir = dir
+	r.cache = map[string]cacheEntry{}
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch re-syncs the directory every interval until ctx is done.
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	if r.cfg.File == "" && r.cfg.LDIFFile == "" {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := r.Sync(); err != nil {
+			log.Printf("groups: sync failed, keeping previous directory: %v", err)
+		}
+	}
+}
+
+// Expand returns pr with its groups replaced by the effective group set.
+func (r *Resolver) Expand(pr types.Principal) types.Principal {
+	key := cacheKey(pr)
+	now := time.Now()
+	r.mu.RLock()
+	e, ok := r.cache[key]
+	dir := r.dir
+	r.mu.RUnlock()
+	if !ok || now.After(e.expires) {
+		exps := dir.expand(pr, pr.Groups, r.cfg.Hierarchy)
+		e = cacheEntry{groups: make([]string, 0, len(exps)), expires: now.Add(r.cfg.CacheTTL)}
+		for _, x := range exps {
+			e.groups = append(e.groups, x.Group)
+		}
+		r.mu.Lock()
+		if len(r.cache) >= maxCacheEntries {
+			r.cache = map[string]cacheEntry{}
+		}
+		r.cache[key] = e
+		r.mu.Unlock()
+	}
+	pr.Groups = e.groups
+	return pr
+}
+
+// Explain reports every effective group of pr and how it was derived.
+func (r *Resolver) Explain(pr types.Principal) []Expansion {
+	r.mu.RLock()
+	dir := r.dir
+	r.mu.RUnlock()
+	return dir.expand(pr, pr.Groups, r.cfg.Hierarchy)
+}
+
+func cacheKey(pr types.Principal) string {
+	gs := append([]string(nil), pr.Groups...)
+	sort.Strings(gs)
+	return string(pr.Type) + "\x00" + pr.ID + "\x00" + strings.Join(gs, "\x00")
+}
+
+func appendUnique(dst []string, xs ...string) []string {
+	for _, x := range xs {
+		dup := false
+		for _, y := range dst {
+			if x == y {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			dst = append(dst, x)
+		}
+	}
+	return dst
+}
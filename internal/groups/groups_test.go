@@ -0,0 +1,138 @@
+package groups
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+func newTestResolver(t *testing.T, directory, ldif string) *Resolver {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := Config{Hierarchy: true, CacheTTL: time.Minute}
+	if directory != "" {
+		cfg.File = filepath.Join(dir, "groups.json")
+		if err := os.WriteFile(cfg.File, []byte(directory), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ldif != "" {
+		cfg.LDIFFile = filepath.Join(dir, "groups.ldif")
+		if err := os.WriteFile(cfg.LDIFFile, []byte(ldif), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := NewResolver(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func sorted(xs []string) []string {
+	out := append([]string(nil), xs...)
+	sort.Strings(out)
+	return out
+}
+
+func TestResolver_ExpandTransitive(t *testing.T) {
+	r := newTestResolver(t, `{
+  "groups": {"team-a": ["engineering"], "engineering": ["staff"], "staff": ["team-a"]},
+  "members": {"internal_user:bob": ["oncall"]}
+}`, "")
+	got := r.Expand(types.Principal{ID: "alice", Groups: []string{"team-a"}})
+	if want := []string{"engineering", "staff", "team-a"}; !reflect.DeepEqual(sorted(got.Groups), want) {
+		t.Fatalf("groups = %v, want %v (cycles must terminate)", got.Groups, want)
+	}
+	got = r.Expand(types.Principal{Type: types.PrincipalInternalUser, ID: "bob"})
+	if !reflect.DeepEqual(got.Groups, []string{"oncall"}) {
+		t.Fatalf("directory membership not applied: %v", got.Groups)
+	}
+	for _, typ := range []types.PrincipalType{types.PrincipalCustomerUser, types.PrincipalService} {
+		if got := r.Expand(types.Principal{Type: typ, ID: "bob"}); len(got.Groups) != 0 {
+			t.Fatalf("%s bob must not inherit the internal user's groups, got %v", typ, got.Groups)
+		}
+	}
+}
+
+func TestResolver_Hierarchy(t *testing.T) {
+	r := newTestResolver(t, "", "")
+	got := r.Expand(types.Principal{Groups: []string{"customer:acme:support:emea"}})
+	want := []string{"customer:acme", "customer:acme:support", "customer:acme:support:emea"}
+	if !reflect.DeepEqual(sorted(got.Groups), want) {
+		t.Fatalf("groups = %v, want %v", got.Groups, want)
+	}
+	r.cfg.Hierarchy = false
+	r.cache = map[string]cacheEntry{}
+	if got := r.Expand(types.Principal{Groups: []string{"customer:acme:support"}}); len(got.Groups) != 1 {
+		t.Fatalf("hierarchy disabled, got %v", got.Groups)
+	}
+}
+
+func TestResolver_ExplainAndSync(t *testing.T) {
+	r := newTestResolver(t, `{"groups": {"team-a": ["engineering"]}}`, "")
+	exps := r.Explain(types.Principal{Groups: []string{"team-a"}})
+	want := []Expansion{
+		{Group: "team-a", Source: SourceDirect},
+		{Group: "engineering", Source: SourceDirectory, Via: "team-a"},
+	}
+	if !reflect.DeepEqual(exps, want) {
+		t.Fatalf("Explain = %+v, want %+v", exps, want)
+	}
+
+	pr := types.Principal{Groups: []string{"team-a"}}
+	if got := r.Expand(pr); len(got.Groups) != 2 {
+		t.Fatalf("unexpected groups %v", got.Groups)
+	}
+	if err := os.WriteFile(r.cfg.File, []byte(`{"groups": {}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Expand(pr); len(got.Groups) != 1 {
+		t.Fatalf("sync must drop cached expansions, got %v", got.Groups)
+	}
+	for _, bad := range []string{`not json`, `{"members": {"bob": ["oncall"]}}`} {
+		if err := os.WriteFile(r.cfg.File, []byte(bad), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Sync(); err == nil {
+			t.Fatalf("expected parse error for %s", bad)
+		}
+	}
+}
+
+func TestParseLDIF(t *testing.T) {
+	d, err := ParseLDIF(strings.NewReader(`# export
+dn: cn=engineering,ou=groups,dc=example,dc=com
+objectClass: groupOfNames
+member: cn=team-a,ou=groups,dc=example,dc=com
+member: uid=alice,ou=people,dc=example,dc=com
+
+dn: uid=alice,ou=people,dc=example,dc=com
+objectClass: person
+
+dn: cn=team-a,ou=groups,dc=exa
+ mple,dc=com
+uniqueMember: uid=bob,ou=people,dc=example,dc=com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Parents["team-a"]; !reflect.DeepEqual(got, []string{"engineering"}) {
+		t.Fatalf("parents(team-a) = %v", got)
+	}
+	if got := d.Members["internal_user:alice"]; !reflect.DeepEqual(got, []string{"engineering"}) {
+		t.Fatalf("members(alice) = %v", got)
+	}
+	if got := d.Members["internal_user:bob"]; !reflect.DeepEqual(got, []string{"team-a"}) {
+		t.Fatalf("members(bob) = %v", got)
+	}
+}
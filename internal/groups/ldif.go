@@ -0,0 +1,99 @@
+package groups
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+var errLDIF = errors.New("malformed LDIF")
+
+// ParseLDIF reads group entries from an LDIF export, a local stand-in for an
+// LDAP directory. Every entry with a cn= DN is a group; its member values are
+// either groups (cn=...) or internal users (uid=...):
+//
+//	dn: cn=engineering,ou=groups,dc=example,dc=com
+//	member: cn=team-a,ou=groups,dc=example,dc=com
+//	member: uid=alice,ou=people,dc=example,dc=com
+//
+// Other attributes and entries are ignored.
+func ParseLDIF(r io.Reader) (*Directory, error) {
+	d := &Directory{Parents: map[string][]string{}, Members: map[string][]string{}}
+	sc := bufio.NewScanner(r)
+	var group string
+	line := 0
+	var prev *string
+	var attrs []string
+	flush := func() error {
+		defer func() { group, attrs = "", nil }()
+		for _, a := range attrs {
+			name, value, ok := strings.Cut(a, ":")
+			if !ok {
+				return fmt.Errorf("%w: line %d", errLDIF, line)
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "dn":
+				group = rdnValue(value, "cn")
+			}
+		}
+		if group == "" {
+			return nil
+		}
+		for _, a := range attrs {
+			name, value, _ := strings.Cut(a, ":")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "member" && name != "uniquemember" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if sub := rdnValue(value, "cn"); sub != "" {
+				d.Parents[sub] = appendUnique(d.Parents[sub], group)
+			} else if uid := rdnValue(value, "uid"); uid != "" {
+				key := MemberKey(types.PrincipalInternalUser, uid)
+				d.Members[key] = appendUnique(d.Members[key], group)
+			}
+		}
+		return nil
+	}
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		switch {
+		case strings.HasPrefix(text, "#"):
+			continue
+		case strings.TrimSpace(text) == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			prev = nil
+		case strings.HasPrefix(text, " ") && prev != nil:
+			// RFC 2849 line folding.
+			*prev += text[1:]
+		default:
+			attrs = append(attrs, text)
+			prev = &attrs[len(attrs)-1]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// rdnValue returns the value of the first RDN of dn if its attribute is attr.
+func rdnValue(dn, attr string) string {
+	first, _, _ := strings.Cut(dn, ",")
+	k, v, ok := strings.Cut(first, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(k), attr) {
+		return ""
+	}
+	return strings.TrimSpace(v)
+}
@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type Completion struct {
+	Content string `json:"content"`
+	Model   string `json:"model"`
+	Usage   Usage  `json:"usage"`
+}
+
+// Completer generates a chat completion for a list of messages.
+// Stream delivers the answer incrementally through onDelta and returns the
+// assembled completion; an error from onDelta aborts the stream.
+type Completer interface {
+	Complete(ctx context.Context, msgs []Message) (Completion, error)
+	Stream(ctx context.Context, msgs []Message, onDelta func(string) error) (Completion, error)
+}
+
+// OpenAI talks to any OpenAI-compatible /chat/completions endpoint
+// (OpenAI, Azure-style proxies, vLLM, Ollama, ...).
+type OpenAI struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+// NewOpenAI bounds Complete calls by timeout. Streams run as long as their
+// context allows, so the caller sets their deadline.
+func NewOpenAI(baseURL, apiKey, model string, timeout time.Duration) *OpenAI {
+	return &OpenAI{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		timeout:    timeout,
+		httpClient: &http.Client{},
+	}
+}
+
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Temperature   float64        `json:"temperature"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions map[string]any `json:"stream_options,omitempty"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+type chatChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta Message `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+func (o *OpenAI) post(ctx context.Context, body chatRequest) (*http.Response, error) {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		x, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("chat completion status %d: %s", resp.StatusCode, string(x))
+	}
+	return resp, nil
+}
+
+// Stream uses the server-sent events variant of /chat/completions. Usage is
+// only reported by servers that honour stream_options.include_usage.
+func (o *OpenAI) Stream(ctx context.Context, msgs []Message, onDelta func(string) error) (Completion, error) {
+	resp, err := o.post(ctx, chatRequest{
+		Model:         o.model,
+		Messages:      msgs,
+		Stream:        true,
+		StreamOptions: map[string]any{"include_usage": true},
+	})
+	if err != nil {
+		return Completion{}, err
+	}
+	defer resp.Body.Close()
+
+	var out Completion
+	var content strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return Completion{}, fmt.Errorf("chat completion stream: %w", err)
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = *chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(c.Delta.Content)
+			if err := onDelta(c.Delta.Content); err != nil {
+				return Completion{}, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Completion{}, err
+	}
+	out.Content = content.String()
+	return out, nil
+}
+
+func (o *OpenAI) Complete(ctx context.Context, msgs []Message) (Completion, error) {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	resp, err := o.post(ctx, chatRequest{Model: o.model, Messages: msgs})
+	if err != nil {
+		return Completion{}, err
+	}
+	defer resp.Body.Close()
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Completion{}, err
+	}
+	if len(out.Choices) == 0 {
+		return Completion{}, fmt.Errorf("chat completion: no choices")
+	}
+	return Completion{Content: out.Choices[0].Message.Content, Model: out.Model, Usage: out.Usage}, nil
+}
+
+// FakeCompleter is a deterministic completer for local dev/tests when no LLM is configured.
+// It answers by quoting the start of the first numbered source in the prompt.
+// It should not be used in production.
+type FakeCompleter struct{}
+
+func NewFake() *FakeCompleter { return &FakeCompleter{} }
+
+func (f *FakeCompleter) Complete(ctx context.Context, msgs []Message) (Completion, error) {
+	_ = ctx
+	var prompt string
+	if len(msgs) > 0 {
+		prompt = msgs[len(msgs)-1].Content
+	}
+	answer := "I don't know based on the provided sources."
+	if i := strings.Index(prompt, "[1]"); i >= 0 {
+		// Skip the "[1] title" header line and quote the first line of the source text.
+		rest := prompt[i:]
+		lines := strings.SplitN(rest, "\n", 3)
+		if len(lines) >= 2 {
+			quote := []rune(strings.TrimSpace(lines[1]))
+			if len(quote) > 200 {
+				quote = quote[:200]
+			}
+			answer = fmt.Sprintf("According to the sources: %s [1]", string(quote))
+		}
+	}
+	promptTokens := countWords(msgs)
+	completionTokens := len(strings.Fields(answer))
+	return Completion{
+		Content: answer,
+		Model:   "fake",
+		Usage:   Usage{PromptTokens: promptTokens, CompletionTokens: completionTokens, TotalTokens: promptTokens + completionTokens},
+	}, nil
+}
+
+// Stream emits the Complete answer word by word.
+func (f *FakeCompleter) Stream(ctx context.Context, msgs []Message, onDelta func(string) error) (Completion, error) {
+	comp, err := f.Complete(ctx, msgs)
+	if err != nil {
+		return Completion{}, err
+	}
+	for i, word := range strings.Fields(comp.Content) {
+		if err := ctx.Err(); err != nil {
+			return Completion{}, err
+		}
+		if i > 0 {
+			word = " " + word
+		}
+		if err := onDelta(word); err != nil {
+			return Completion{}, err
+		}
+	}
+	return comp, nil
+}
+
+func countWords(msgs []Message) int {
+	n := 0
+	for _, m := range msgs {
+		n += len(strings.Fields(m.Content))
+	}
+	return n
+}
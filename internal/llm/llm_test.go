@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenAIStream_ParsesDeltasAndUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, tok := range []string{"Hello", " world", " [1]"} {
+			fmt.Fprintf(w, "data: {\"model\":\"m1\",\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", tok)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":3,\"total_tokens\":10}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	var deltas []string
+	comp, err := NewOpenAI(srv.URL, "k", "m1", 5*time.Second).Stream(context.Background(), []Message{{Role: "user", Content: "hi"}}, func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if comp.Content != "Hello world [1]" || len(deltas) != 3 {
+		t.Fatalf("unexpected content %q deltas %v", comp.Content, deltas)
+	}
+	if comp.Model != "m1" || comp.Usage.TotalTokens != 10 {
+		t.Fatalf("unexpected model/usage: %+v", comp)
+	}
+}
+
+func TestFakeStream_StopsWhenCallbackFails(t *testing.T) {
+	stop := errors.New("client gone")
+	n := 0
+	_, err := NewFake().Stream(context.Background(), []Message{{Role: "user", Content: "[1] Doc\nsome long source text here"}}, func(string) error {
+		n++
+		if n == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || n != 2 {
+		t.Fatalf("expected stream to stop after callback error, got err=%v n=%d", err, n)
+	}
+}
+
+func TestFakeComplete_QuotesFirstSource(t *testing.T) {
+	comp, err := NewFake().Complete(context.Background(), []Message{{Role: "user", Content: "Sources:\n\n[1] Doc\nAlpha is first.\n\nQuestion: alpha?"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(comp.Content, "Alpha is first.") || !strings.Contains(comp.Content, "[1]") {
+		t.Fatalf("unexpected answer: %q", comp.Content)
+	}
+}
@@ -0,0 +1,303 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// PayloadKey is the payload namespace document metadata is stored under, so
+// caller-supplied keys can never collide with gateway fields such as acl_allow.
+const PayloadKey = "meta"
+
+type FieldType string
+
+const (
+	TypeKeyword     FieldType = "keyword"
+	TypeKeywordList FieldType = "keyword[]"
+	TypeInteger     FieldType = "integer"
+	TypeFloat       FieldType = "float"
+	TypeBool        FieldType = "bool"
+)
+
+type Field struct {
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required"`
+	Enum     []string  `json:"enum"`
+}
+
+type ProjectSchema struct {
+	Fields map[string]Field `json:"fields"`
+}
+
+// Schema holds per-project metadata schemas. Projects without an entry fall
+// back to Default; if that is nil too, metadata is rejected for the project.
+type Schema struct {
+	Default  *ProjectSchema           `json:"default"`
+	Projects map[string]ProjectSchema `json:"projects"`
+}
+
+const (
+	maxKeys      = 64
+	maxStringLen = 1024
+	maxListLen   = 64
+)
+
+var keyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ValidKey reports whether k is an acceptable metadata key.
+func ValidKey(k string) bool { return keyRe.MatchString(k) }
+
+func Load(path string) (*Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s.Default != nil {
+		if err := s.Default.check(); err != nil {
+			return nil, fmt.Errorf("default schema: %w", err)
+		}
+	}
+	// Fields share one payload index per key, so every project must agree on
+	// a field's type.
+	fieldTypes := map[string]FieldType{}
+	declared := map[string]string{}
+	if s.Default != nil {
+		for k, f := range s.Default.Fields {
+			fieldTypes[k], declared[k] = f.Type, "default schema"
+		}
+	}
+	for _, p := range SortedKeys(s.Projects) {
+		ps := s.Projects[p]
+		if err := ps.check(); err != nil {
+			return nil, fmt.Errorf("project %s: %w", p, err)
+		}
+		for _, k := range SortedKeys(ps.Fields) {
+			t := ps.Fields[k].Type
+			if prev, ok := fieldTypes[k]; ok && prev != t {
+				return nil, fmt.Errorf("project %s: field %s is %s but %s in the %s", p, k, t, prev, declared[k])
+			}
+			fieldTypes[k], declared[k] = t, "project "+p
+		}
+	}
+	return &s, nil
+}
+
+func (ps ProjectSchema) check() error {
+	for k, f := range ps.Fields {
+		if !ValidKey(k) {
+			return fmt.Errorf("invalid field name %q", k)
+		}
+		switch f.Type {
+		case TypeKeyword, TypeKeywordList, TypeInteger, TypeFloat, TypeBool:
+		default:
+			return fmt.Errorf("field %s: unknown type %q", k, f.Type)
+		}
+	}
+	return nil
+}
+
+// Validate checks md against the schema for projectID and returns a
+// normalized copy (integers as int64, keyword lists as []string).
+// A nil Schema only enforces shape: valid keys and scalar or string-list values.
+func (s *Schema) Validate(projectID string, md map[string]any) (map[string]any, error) {
+	if len(md) > maxKeys {
+		return nil, fmt.Errorf("more than %d metadata keys", maxKeys)
+	}
+	if s == nil {
+		return validateShape(md)
+	}
+	ps, ok := s.Projects[projectID]
+	if !ok {
+		if s.Default == nil {
+			if len(md) == 0 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("no metadata schema for project %s", projectID)
+		}
+		ps = *s.Default
+	}
+
+	out := make(map[string]any, len(md))
+	for _, k := range SortedKeys(md) {
+		f, ok := ps.Fields[k]
+		if !ok {
+			return nil, fmt.Errorf("unknown metadata key %q", k)
+		}
+		v, err := f.normalize(md[k])
+		if err != nil {
+			return nil, fmt.Errorf("metadata key %q: %w", k, err)
+		}
+		out[k] = v
+	}
+	for _, k := range SortedKeys(ps.Fields) {
+		if _, ok := md[k]; !ok && ps.Fields[k].Required {
+			return nil, fmt.Errorf("missing required metadata key %q", k)
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// IndexedFields returns the payload index type for every schema field across
+// all projects, keyed by its full payload path (e.g. "meta.tags"). Load
+// guarantees that projects agree on each field's type.
+func (s *Schema) IndexedFields() map[string]FieldType {
+	out := map[string]FieldType{}
+	if s == nil {
+		return out
+	}
+	add := func(ps ProjectSchema) {
+		for k, f := range ps.Fields {
+			out[PayloadKey+"."+k] = f.Type
+		}
+	}
+	if s.Default != nil {
+		add(*s.Default)
+	}
+	for _, ps := range s.Projects {
+		add(ps)
+	}
+	return out
+}
+
+// IndexSchema maps a field type to the Qdrant payload index schema name.
+func (t FieldType) IndexSchema() string {
+	switch t {
+	case TypeInteger:
+		return "integer"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	default:
+		return "keyword"
+	}
+}
+
+func (f Field) normalize(v any) (any, error) {
+	switch f.Type {
+	case TypeKeyword:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		return s, f.checkString(s)
+	case TypeKeywordList:
+		list, err := toStringList(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range list {
+			if err := f.checkString(s); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case TypeInteger:
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, fmt.Errorf("expected integer")
+		}
+		return int64(n), nil
+	case TypeFloat:
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected number")
+		}
+		return n, nil
+	case TypeBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", f.Type)
+}
+
+func (f Field) checkString(s string) error {
+	if len(s) > maxStringLen {
+		return fmt.Errorf("value longer than %d bytes", maxStringLen)
+	}
+	if len(f.Enum) == 0 {
+		return nil
+	}
+	for _, e := range f.Enum {
+		if e == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("value %q not allowed", s)
+}
+
+func validateShape(md map[string]any) (map[string]any, error) {
+	if len(md) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]any, len(md))
+	for _, k := range SortedKeys(md) {
+		if !ValidKey(k) {
+			return nil, fmt.Errorf("invalid metadata key %q", k)
+		}
+		switch x := md[k].(type) {
+		case string:
+			if len(x) > maxStringLen {
+				return nil, fmt.Errorf("metadata key %q: value longer than %d bytes", k, maxStringLen)
+			}
+			out[k] = x
+		case bool, float64:
+			out[k] = x
+		case []any:
+			list, err := toStringList(x)
+			if err != nil {
+				return nil, fmt.Errorf("metadata key %q: %w", k, err)
+			}
+			out[k] = list
+		default:
+			return nil, fmt.Errorf("metadata key %q: unsupported value type", k)
+		}
+	}
+	return out, nil
+}
+
+func toStringList(v any) ([]string, error) {
+	raw, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected list of strings")
+	}
+	if len(raw) > maxListLen {
+		return nil, fmt.Errorf("more than %d values", maxListLen)
+	}
+	out := make([]string, 0, len(raw))
+	for _, x := range raw {
+		s, ok := x.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected list of strings")
+		}
+		if len(s) > maxStringLen {
+			return nil, fmt.Errorf("value longer than %d bytes", maxStringLen)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// SortedKeys returns the keys of m in order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testSchema() *Schema {
+	return &Schema{
+		Projects: map[string]ProjectSchema{
+			"proj1": {Fields: map[string]Field{
+				"tags":     {Type: TypeKeywordList},
+				"language": {Type: TypeKeyword, Required: true, Enum: []string{"en", "de"}},
+				"priority": {Type: TypeInteger},
+			}},
+		},
+	}
+}
+
+func TestValidate_NormalizesKnownFields(t *testing.T) {
+	out, err := testSchema().Validate("proj1", map[string]any{
+		"tags":     []any{"billing", "sso"},
+		"language": "en",
+		"priority": float64(3),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tags, ok := out["tags"].([]string); !ok || len(tags) != 2 {
+		t.Fatalf("expected tags as []string, got %#v", out["tags"])
+	}
+	if out["priority"] != int64(3) {
+		t.Fatalf("expected integer priority, got %#v", out["priority"])
+	}
+}
+
+func TestValidate_Rejects(t *testing.T) {
+	s := testSchema()
+	cases := []map[string]any{
+		{"language": "fr"},
+		{"language": "en", "owner": "x"},
+		{"language": "en", "priority": 1.5},
+		{"tags": []any{"a"}},
+	}
+	for _, md := range cases {
+		if _, err := s.Validate("proj1", md); err == nil {
+			t.Fatalf("expected %v to be rejected", md)
+		}
+	}
+	if _, err := s.Validate("other", map[string]any{"language": "en"}); err == nil {
+		t.Fatalf("expected project without schema to reject metadata")
+	}
+}
+
+func TestValidate_NilSchemaChecksShape(t *testing.T) {
+	var s *Schema
+	if _, err := s.Validate("p", map[string]any{"team": "core", "tags": []any{"a"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Validate("p", map[string]any{"Bad Key": "x"}); err == nil {
+		t.Fatalf("expected invalid key to be rejected")
+	}
+	if _, err := s.Validate("p", map[string]any{"nested": map[string]any{"a": 1}}); err == nil {
+		t.Fatalf("expected nested object to be rejected")
+	}
+}
+
+func TestLoad_RejectsConflictingFieldTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	write := func(schema string) {
+		if err := os.WriteFile(path, []byte(schema), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"default":{"fields":{"priority":{"type":"integer"}}},"projects":{"proj1":{"fields":{"priority":{"type":"integer"},"tags":{"type":"keyword[]"}}}}}`)
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.IndexedFields(); got["meta.priority"] != TypeInteger || got["meta.tags"] != TypeKeywordList {
+		t.Fatalf("unexpected indexed fields %v", got)
+	}
+
+	write(`{"default":{"fields":{"priority":{"type":"integer"}}},"projects":{"proj1":{"fields":{"priority":{"type":"keyword"}}}}}`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "priority") {
+		t.Fatalf("expected a conflict on priority, got %v", err)
+	}
+	write(`{"projects":{"a":{"fields":{"level":{"type":"float"}}},"b":{"fields":{"level":{"type":"integer"}}}}}`)
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected a conflict between projects")
+	}
+}
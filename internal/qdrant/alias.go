@@ -0,0 +1,129 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+// ResolveAlias returns the collection alias points to, or "" if there is no
+// such alias.
+func (c *Client) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	var out struct {
+		Result struct {
+			Aliases []struct {
+				AliasName      string `json:"alias_name"`
+				CollectionName string `json:"collection_name"`
+			} `json:"aliases"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/aliases", nil, &out); err != nil {
+		return "", err
+	}
+	for _, a := range out.Result.Aliases {
+		if a.AliasName == alias {
+			return a.CollectionName, nil
+		}
+	}
+	return "", nil
+}
+
+// SwitchAlias points alias at collection. Qdrant applies the delete and
+// create actions atomically, so readers never see the alias missing.
+func (c *Client) SwitchAlias(ctx context.Context, alias, collection string) error {
+	current, err := c.ResolveAlias(ctx, alias)
+	if err != nil {
+		return err
+	}
+	var actions []any
+	if current != "" {
+		actions = append(actions, map[string]any{"delete_alias": map[string]any{"alias_name": alias}})
+	}
+	actions = append(actions, map[string]any{"create_alias": map[string]any{"collection_name": collection, "alias_name": alias}})
+	return c.post(ctx, "/collections/aliases", map[string]any{"actions": actions}, nil)
+}
+
+func (c *Client) DeleteCollection(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/collections/%s", name), nil, nil)
+}
+
+// ListCollections returns the names of all physical collections.
+func (c *Client) ListCollections(ctx context.Context) ([]string, error) {
+	var out struct {
+		Result struct {
+			Collections []struct {
+				Name string `json:"name"`
+			} `json:"collections"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/collections", nil, &out); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(out.Result.Collections))
+	for _, col := range out.Result.Collections {
+		names = append(names, col.Name)
+	}
+	return names, nil
+}
+
+func (c *GRPCClient) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	var resp *pb.ListAliasesResponse
+	err := c.call(ctx, "list aliases", func(ctx context.Context) (err error) {
+		resp, err = c.collections.ListAliases(ctx, &pb.ListAliasesRequest{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, a := range resp.GetAliases() {
+		if a.GetAliasName() == alias {
+			return a.GetCollectionName(), nil
+		}
+	}
+	return "", nil
+}
+
+func (c *GRPCClient) SwitchAlias(ctx context.Context, alias, collection string) error {
+	current, err := c.ResolveAlias(ctx, alias)
+	if err != nil {
+		return err
+	}
+	var actions []*pb.AliasOperations
+	if current != "" {
+		actions = append(actions, &pb.AliasOperations{Action: &pb.AliasOperations_DeleteAlias{
+			DeleteAlias: &pb.DeleteAlias{AliasName: alias},
+		}})
+	}
+	actions = append(actions, &pb.AliasOperations{Action: &pb.AliasOperations_CreateAlias{
+		CreateAlias: &pb.CreateAlias{CollectionName: collection, AliasName: alias},
+	}})
+	return c.call(ctx, "update aliases", func(ctx context.Context) error {
+		_, err := c.collections.UpdateAliases(ctx, &pb.ChangeAliases{Actions: actions})
+		return err
+	})
+}
+
+func (c *GRPCClient) DeleteCollection(ctx context.Context, name string) error {
+	return c.call(ctx, "delete collection", func(ctx context.Context) error {
+		_, err := c.collections.Delete(ctx, &pb.DeleteCollection{CollectionName: name})
+		return err
+	})
+}
+
+func (c *GRPCClient) ListCollections(ctx context.Context) ([]string, error) {
+	var resp *pb.ListCollectionsResponse
+	err := c.call(ctx, "list collections", func(ctx context.Context) (err error) {
+		resp, err = c.collections.List(ctx, &pb.ListCollectionsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp.GetCollections()))
+	for _, col := range resp.GetCollections() {
+		names = append(names, col.GetName())
+	}
+	return names, nil
+}
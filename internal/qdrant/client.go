@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,10 +14,17 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	guard      *guard
 }
 
 func New(baseURL string, timeout time.Duration) *Client {
-	return &Client{baseURL: baseURL, httpClient: &http.Client{Timeout: timeout}}
+	return &Client{baseURL: baseURL, httpClient: &http.Client{Timeout: timeout}, guard: newGuard(DefaultRetryPolicy)}
+}
+
+// WithRetryPolicy replaces the retry policy and resets the circuit breaker.
+func (c *Client) WithRetryPolicy(p RetryPolicy) *Client {
+	c.guard = newGuard(p)
+	return c
 }
 
 func (c *Client) EnsureCollection(ctx context.Context, name string, vectorDim int) error {
@@ -26,18 +34,44 @@ func (c *Client) EnsureCollection(ctx context.Context, name string, vectorDim in
 			"distance": "Cosine",
 		},
 	}
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/collections/%s", c.baseURL, name), bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 || resp.StatusCode == 409 {
+	err := c.put(ctx, fmt.Sprintf("/collections/%s", name), body, nil)
+	if errors.Is(err, ErrConflict) {
 		return nil
 	}
-	return fmt.Errorf("ensure collection: status %d", resp.StatusCode)
+	return err
+}
+
+func (c *Client) CollectionInfo(ctx context.Context, name string) (CollectionInfo, error) {
+	var out struct {
+		Result struct {
+			Config struct {
+				Params struct {
+					Vectors json.RawMessage `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/collections/%s", name), nil, &out); err != nil {
+		return CollectionInfo{}, err
+	}
+	var v struct {
+		Size     int    `json:"size"`
+		Distance string `json:"distance"`
+	}
+	if err := json.Unmarshal(out.Result.Config.Params.Vectors, &v); err != nil || v.Size == 0 {
+		return CollectionInfo{}, fmt.Errorf("collection %s: only a single unnamed vector is supported", name)
+	}
+	return CollectionInfo{VectorSize: v.Size, Distance: v.Distance}, nil
+}
+
+// CreatePayloadIndex creates a payload index on field. Qdrant treats re-creating
+// an existing index as a no-op, so this is safe to call on every startup.
+func (c *Client) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	body := map[string]any{
+		"field_name":   field,
+		"field_schema": schema,
+	}
+	return c.put(ctx, fmt.Sprintf("/collections/%s/index?wait=true", collection), body, nil)
 }
 
 func (c *Client) Upsert(ctx context.Context, collection string, points []Point) error {
@@ -64,13 +98,25 @@ type SearchResponse struct {
 	Result []SearchResult `json:"result"`
 }
 
-func (c *Client) Search(ctx context.Context, collection string, vector []float32, filter Filter, limit int) ([]SearchResult, error) {
+// SearchOptions are optional search parameters passed through to Qdrant.
+type SearchOptions struct {
+	Offset         int
+	ScoreThreshold *float64
+}
+
+func (c *Client) Search(ctx context.Context, collection string, vector []float32, filter Filter, limit int, opts SearchOptions) ([]SearchResult, error) {
 	body := map[string]any{
 		"vector":       vector,
 		"limit":        limit,
 		"with_payload": true,
 		"filter":       filter,
 	}
+	if opts.Offset > 0 {
+		body["offset"] = opts.Offset
+	}
+	if opts.ScoreThreshold != nil {
+		body["score_threshold"] = *opts.ScoreThreshold
+	}
 	var out SearchResponse
 	if err := c.post(ctx, fmt.Sprintf("/collections/%s/points/search", collection), body, &out); err != nil {
 		return nil, err
@@ -78,6 +124,52 @@ func (c *Client) Search(ctx context.Context, collection string, vector []float32
 	return out.Result, nil
 }
 
+type ScrollResult struct {
+	Points         []ScoredPoint `json:"points"`
+	NextPageOffset any           `json:"next_page_offset"`
+}
+
+// ScoredPoint is a point as returned by scroll; Vector is only set when requested.
+type ScoredPoint struct {
+	ID      any            `json:"id"`
+	Payload map[string]any `json:"payload"`
+	Vector  []float32      `json:"vector,omitempty"`
+}
+
+// Scroll pages through points matching filter. Pass the previous
+// NextPageOffset as offset; a nil NextPageOffset means the last page.
+func (c *Client) Scroll(ctx context.Context, collection string, filter Filter, limit int, offset any, withVector bool) (ScrollResult, error) {
+	body := map[string]any{
+		"filter":       filter,
+		"limit":        limit,
+		"with_payload": true,
+		"with_vector":  withVector,
+	}
+	if offset != nil {
+		body["offset"] = offset
+	}
+	var out struct {
+		Result ScrollResult `json:"result"`
+	}
+	if err := c.post(ctx, fmt.Sprintf("/collections/%s/points/scroll", collection), body, &out); err != nil {
+		return ScrollResult{}, err
+	}
+	return out.Result, nil
+}
+
+func (c *Client) Count(ctx context.Context, collection string, filter Filter) (int, error) {
+	body := map[string]any{"filter": filter, "exact": true}
+	var out struct {
+		Result struct {
+			Count int `json:"count"`
+		} `json:"result"`
+	}
+	if err := c.post(ctx, fmt.Sprintf("/collections/%s/points/count", collection), body, &out); err != nil {
+		return 0, err
+	}
+	return out.Result.Count, nil
+}
+
 func (c *Client) post(ctx context.Context, path string, body any, out any) error {
 	return c.do(ctx, http.MethodPost, path, body, out)
 }
@@ -86,18 +178,50 @@ func (c *Client) put(ctx context.Context, path string, body any, out any) error
 	return c.do(ctx, http.MethodPut, path, body, out)
 }
 
+// do retries every call: all requests the gateway sends are idempotent.
+// Upserts carry client-generated IDs, payload updates and deletes select by
+// filter and converge, and everything else is a read. The exception,
+// snapshot creation, uses doOnce.
 func (c *Client) do(ctx context.Context, method, path string, body any, out any) error {
-	b, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(b))
+	return c.request(ctx, true, method, path, body, out)
+}
+
+// doOnce is do without retries, for calls that must not be repeated.
+func (c *Client) doOnce(ctx context.Context, method, path string, body any, out any) error {
+	return c.request(ctx, false, method, path, body, out)
+}
+
+func (c *Client) request(ctx context.Context, idempotent bool, method, path string, body any, out any) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.guard.run(ctx, idempotent, func(ctx context.Context) error {
+		return c.send(ctx, method, path, b, out)
+	})
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out any) error {
+	op := method + " " + path
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return err
+		}
+		return &Error{Op: op, Kind: ErrUnavailable, Err: err}
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		x, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("qdrant %s %s status %d: %s", method, path, resp.StatusCode, string(x))
+		return &Error{Op: op, Status: resp.StatusCode, Kind: statusKind(resp.StatusCode), Body: string(x)}
 	}
 	if out != nil {
 		return json.NewDecoder(resp.Body).Decode(out)
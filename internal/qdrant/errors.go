@@ -0,0 +1,84 @@
+package qdrant
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error kinds. Callers match them with errors.Is; *Error carries the details.
+var (
+	ErrNotFound    = errors.New("qdrant: not found")
+	ErrConflict    = errors.New("qdrant: conflict")
+	ErrBadRequest  = errors.New("qdrant: bad request")
+	ErrUnavailable = errors.New("qdrant: unavailable")
+
+	// ErrCircuitOpen is returned without contacting Qdrant while the
+	// circuit breaker is open. It is also an ErrUnavailable.
+	ErrCircuitOpen = fmt.Errorf("%w (circuit open)", ErrUnavailable)
+)
+
+// Error is a failed Qdrant call. Body is the raw response and is meant for
+// logs, not for API clients.
+type Error struct {
+	Op     string
+	Status int // HTTP status; 0 for transport and gRPC errors
+	Kind   error
+	Body   string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Status != 0:
+		return fmt.Sprintf("qdrant %s status %d: %s", e.Op, e.Status, e.Body)
+	case e.Err != nil:
+		return fmt.Sprintf("qdrant %s: %v", e.Op, e.Err)
+	default:
+		return fmt.Sprintf("qdrant %s: %v", e.Op, e.Kind)
+	}
+}
+
+func (e *Error) Unwrap() []error {
+	var out []error
+	if e.Kind != nil {
+		out = append(out, e.Kind)
+	}
+	if e.Err != nil {
+		out = append(out, e.Err)
+	}
+	return out
+}
+
+// statusKind classifies an HTTP status. 500 is left unclassified: Qdrant
+// uses it for internal errors that a retry will not fix.
+func statusKind(code int) error {
+	switch code {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrBadRequest
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+func grpcKind(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return ErrConflict
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return ErrBadRequest
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return ErrUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package qdrant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Matcher evaluates a filter against points the way Qdrant would, for the
+// subset of the filter language the gateway emits: must/should/must_not,
+// nested filters, match value/any, range and has_id. Keys may be dotted
+// paths into nested objects; a condition on an array field holds if any
+// element satisfies it. Unsupported conditions are an error rather than a
+// silent match or miss.
+type Matcher struct {
+	f map[string]any
+}
+
+func NewMatcher(f Filter) (*Matcher, error) {
+	norm := map[string]any{}
+	if err := roundTrip(map[string]any(f), &norm); err != nil {
+		return nil, err
+	}
+	return &Matcher{f: norm}, nil
+}
+
+// Match evaluates the filter; payload must hold JSON types (see NormalizePayload).
+func (m *Matcher) Match(id any, payload map[string]any) (bool, error) {
+	return evalClauses(m.f, fmt.Sprint(id), payload)
+}
+
+// Eval is NewMatcher(f).Match(id, payload).
+func Eval(f Filter, id any, payload map[string]any) (bool, error) {
+	m, err := NewMatcher(f)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(id, payload)
+}
+
+// NormalizePayload converts a payload to the JSON types Qdrant returns
+// (float64 numbers, []any lists, map[string]any objects).
+func NormalizePayload(p map[string]any) (map[string]any, error) {
+	out := map[string]any{}
+	if err := roundTrip(p, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func roundTrip(in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func evalClauses(f map[string]any, id string, payload map[string]any) (bool, error) {
+	for k := range f {
+		if k != "must" && k != "should" && k != "must_not" {
+			return false, fmt.Errorf("unsupported filter clause %q", k)
+		}
+	}
+	must, _ := f["must"].([]any)
+	for _, c := range must {
+		ok, err := evalCondition(c, id, payload)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	mustNot, _ := f["must_not"].([]any)
+	for _, c := range mustNot {
+		ok, err := evalCondition(c, id, payload)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	should, _ := f["should"].([]any)
+	if len(should) == 0 {
+		return true, nil
+	}
+	for _, c := range should {
+		ok, err := evalCondition(c, id, payload)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func evalCondition(c any, id string, payload map[string]any) (bool, error) {
+	m, ok := c.(map[string]any)
+	if !ok {
+		return false, fmt.Errorf("unsupported condition %v", c)
+	}
+	if ids, ok := m["has_id"]; ok {
+		list, _ := ids.([]any)
+		for _, x := range list {
+			if fmt.Sprint(x) == id {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	key, ok := m["key"].(string)
+	if !ok {
+		return evalClauses(m, id, payload)
+	}
+	values := pathValues(payload, key)
+	if match, ok := m["match"].(map[string]any); ok {
+		if v, ok := match["value"]; ok {
+			return containsValue(values, v), nil
+		}
+		if list, ok := match["any"].([]any); ok {
+			for _, v := range list {
+				if containsValue(values, v) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("unsupported match %v", match)
+	}
+	if r, ok := m["range"].(map[string]any); ok {
+		for _, v := range values {
+			if n, ok := v.(float64); ok && inRange(n, r) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported condition %v", m)
+}
+
+// pathValues resolves a dotted key, flattening arrays along the way.
+func pathValues(payload map[string]any, key string) []any {
+	cur := []any{payload}
+	for _, part := range strings.Split(key, ".") {
+		var next []any
+		for _, v := range cur {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch x := obj[part].(type) {
+			case nil:
+			case []any:
+				next = append(next, x...)
+			default:
+				next = append(next, x)
+			}
+		}
+		cur = next
+	}
+	return cur
+}
+
+func containsValue(values []any, v any) bool {
+	for _, pv := range values {
+		if pv == v {
+			return true
+		}
+	}
+	return false
+}
+
+func inRange(n float64, r map[string]any) bool {
+	if b, ok := r["gt"].(float64); ok && !(n > b) {
+		return false
+	}
+	if b, ok := r["gte"].(float64); ok && !(n >= b) {
+		return false
+	}
+	if b, ok := r["lt"].(float64); ok && !(n < b) {
+		return false
+	}
+	if b, ok := r["lte"].(float64); ok && !(n <= b) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,570 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// GRPCClient talks to Qdrant over gRPC (port 6334) with the same method
+// surface as Client. Filters and payloads keep their JSON shapes and are
+// converted to protobuf per call.
+type GRPCClient struct {
+	conn        *grpc.ClientConn
+	points      pb.PointsClient
+	collections pb.CollectionsClient
+	snapshots   pb.SnapshotsClient
+	timeout     time.Duration
+	guard       *guard
+}
+
+// NewGRPC connects lazily; dial errors surface on the first call.
+func NewGRPC(addr string, timeout time.Duration) (*GRPCClient, error) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		conn:        conn,
+		points:      pb.NewPointsClient(conn),
+		collections: pb.NewCollectionsClient(conn),
+		snapshots:   pb.NewSnapshotsClient(conn),
+		timeout:     timeout,
+		guard:       newGuard(DefaultRetryPolicy),
+	}, nil
+}
+
+// WithRetryPolicy replaces the retry policy and resets the circuit breaker.
+func (c *GRPCClient) WithRetryPolicy(p RetryPolicy) *GRPCClient {
+	c.guard = newGuard(p)
+	return c
+}
+
+func (c *GRPCClient) Close() error { return c.conn.Close() }
+
+// call runs fn under the retry policy with a per-attempt timeout. Like the
+// REST client, every call but snapshot creation (callOnce) is treated as
+// idempotent.
+func (c *GRPCClient) call(ctx context.Context, op string, fn func(context.Context) error) error {
+	return c.invoke(ctx, true, op, fn)
+}
+
+func (c *GRPCClient) callOnce(ctx context.Context, op string, fn func(context.Context) error) error {
+	return c.invoke(ctx, false, op, fn)
+}
+
+func (c *GRPCClient) invoke(ctx context.Context, idempotent bool, op string, fn func(context.Context) error) error {
+	return c.guard.run(ctx, idempotent, func(ctx context.Context) error {
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+		return grpcErr(op, fn(ctx))
+	})
+}
+
+func (c *GRPCClient) EnsureCollection(ctx context.Context, name string, vectorDim int) error {
+	err := c.call(ctx, "create collection", func(ctx context.Context) error {
+		_, err := c.collections.Create(ctx, &pb.CreateCollection{
+			CollectionName: name,
+			VectorsConfig: &pb.VectorsConfig{Config: &pb.VectorsConfig_Params{
+				Params: &pb.VectorParams{Size: uint64(vectorDim), Distance: pb.Distance_Cosine},
+			}},
+		})
+		return err
+	})
+	if errors.Is(err, ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+func (c *GRPCClient) CollectionInfo(ctx context.Context, name string) (CollectionInfo, error) {
+	var resp *pb.GetCollectionInfoResponse
+	err := c.call(ctx, "get collection", func(ctx context.Context) (err error) {
+		resp, err = c.collections.Get(ctx, &pb.GetCollectionInfoRequest{CollectionName: name})
+		return err
+	})
+	if err != nil {
+		return CollectionInfo{}, err
+	}
+	params := resp.GetResult().GetConfig().GetParams().GetVectorsConfig().GetParams()
+	if params == nil {
+		return CollectionInfo{}, fmt.Errorf("collection %s: only a single unnamed vector is supported", name)
+	}
+	return CollectionInfo{VectorSize: int(params.GetSize()), Distance: params.GetDistance().String()}, nil
+}
+
+func (c *GRPCClient) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	ft, err := fieldType(schema)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "create field index", func(ctx context.Context) error {
+		_, err := c.points.CreateFieldIndex(ctx, &pb.CreateFieldIndexCollection{
+			CollectionName: collection,
+			Wait:           ptr(true),
+			FieldName:      field,
+			FieldType:      &ft,
+		})
+		return err
+	})
+}
+
+func (c *GRPCClient) Upsert(ctx context.Context, collection string, points []Point) error {
+	ps := make([]*pb.PointStruct, 0, len(points))
+	for _, p := range points {
+		id, err := pointID(p.ID)
+		if err != nil {
+			return err
+		}
+		payload, err := toPayload(p.Payload)
+		if err != nil {
+			return err
+		}
+		ps = append(ps, &pb.PointStruct{
+			Id:      id,
+			Vectors: &pb.Vectors{VectorsOptions: &pb.Vectors_Vector{Vector: &pb.Vector{Data: p.Vector}}},
+			Payload: payload,
+		})
+	}
+	return c.call(ctx, "upsert", func(ctx context.Context) error {
+		_, err := c.points.Upsert(ctx, &pb.UpsertPoints{CollectionName: collection, Wait: ptr(true), Points: ps})
+		return err
+	})
+}
+
+func (c *GRPCClient) SetPayload(ctx context.Context, collection string, payload map[string]any, filter Filter) error {
+	f, err := toFilter(filter)
+	if err != nil {
+		return err
+	}
+	p, err := toPayload(payload)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "set payload", func(ctx context.Context) error {
+		_, err := c.points.SetPayload(ctx, &pb.SetPayloadPoints{
+			CollectionName: collection,
+			Wait:           ptr(true),
+			Payload:        p,
+			PointsSelector: filterSelector(f),
+		})
+		return err
+	})
+}
+
+func (c *GRPCClient) Search(ctx context.Context, collection string, vector []float32, filter Filter, limit int, opts SearchOptions) ([]SearchResult, error) {
+	f, err := toFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.SearchPoints{
+		CollectionName: collection,
+		Vector:         vector,
+		Filter:         f,
+		Limit:          uint64(limit),
+		WithPayload:    &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true}},
+	}
+	if opts.Offset > 0 {
+		req.Offset = ptr(uint64(opts.Offset))
+	}
+	if opts.ScoreThreshold != nil {
+		req.ScoreThreshold = ptr(float32(*opts.ScoreThreshold))
+	}
+	var resp *pb.SearchResponse
+	err = c.call(ctx, "search", func(ctx context.Context) (err error) {
+		resp, err = c.points.Search(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SearchResult, 0, len(resp.GetResult()))
+	for _, p := range resp.GetResult() {
+		out = append(out, SearchResult{
+			ID:      fromPointID(p.GetId()),
+			Score:   float64(p.GetScore()),
+			Payload: fromPayload(p.GetPayload()),
+		})
+	}
+	return out, nil
+}
+
+func (c *GRPCClient) Scroll(ctx context.Context, collection string, filter Filter, limit int, offset any, withVector bool) (ScrollResult, error) {
+	f, err := toFilter(filter)
+	if err != nil {
+		return ScrollResult{}, err
+	}
+	req := &pb.ScrollPoints{
+		CollectionName: collection,
+		Filter:         f,
+		Limit:          ptr(uint32(limit)),
+		WithPayload:    &pb.WithPayloadSelector{SelectorOptions: &pb.WithPayloadSelector_Enable{Enable: true}},
+		WithVectors:    &pb.WithVectorsSelector{SelectorOptions: &pb.WithVectorsSelector_Enable{Enable: withVector}},
+	}
+	if offset != nil {
+		if req.Offset, err = pointID(offset); err != nil {
+			return ScrollResult{}, err
+		}
+	}
+	var resp *pb.ScrollResponse
+	err = c.call(ctx, "scroll", func(ctx context.Context) (err error) {
+		resp, err = c.points.Scroll(ctx, req)
+		return err
+	})
+	if err != nil {
+		return ScrollResult{}, err
+	}
+	out := ScrollResult{Points: make([]ScoredPoint, 0, len(resp.GetResult()))}
+	for _, p := range resp.GetResult() {
+		sp := ScoredPoint{ID: fromPointID(p.GetId()), Payload: fromPayload(p.GetPayload())}
+		if withVector {
+			sp.Vector = p.GetVectors().GetVector().GetData()
+		}
+		out.Points = append(out.Points, sp)
+	}
+	if resp.NextPageOffset != nil {
+		out.NextPageOffset = fromPointID(resp.NextPageOffset)
+	}
+	return out, nil
+}
+
+func (c *GRPCClient) Count(ctx context.Context, collection string, filter Filter) (int, error) {
+	f, err := toFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	var resp *pb.CountResponse
+	err = c.call(ctx, "count", func(ctx context.Context) (err error) {
+		resp, err = c.points.Count(ctx, &pb.CountPoints{CollectionName: collection, Filter: f, Exact: ptr(true)})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.GetResult().GetCount()), nil
+}
+
+func (c *GRPCClient) DeleteByFilter(ctx context.Context, collection string, filter Filter) error {
+	f, err := toFilter(filter)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "delete", func(ctx context.Context) error {
+		_, err := c.points.Delete(ctx, &pb.DeletePoints{CollectionName: collection, Wait: ptr(true), Points: filterSelector(f)})
+		return err
+	})
+}
+
+func grpcErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: "grpc " + op, Kind: grpcKind(err), Err: err}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func filterSelector(f *pb.Filter) *pb.PointsSelector {
+	if f == nil {
+		f = &pb.Filter{}
+	}
+	return &pb.PointsSelector{PointsSelectorOneOf: &pb.PointsSelector_Filter{Filter: f}}
+}
+
+func fieldType(schema string) (pb.FieldType, error) {
+	switch schema {
+	case "keyword":
+		return pb.FieldType_FieldTypeKeyword, nil
+	case "integer":
+		return pb.FieldType_FieldTypeInteger, nil
+	case "float":
+		return pb.FieldType_FieldTypeFloat, nil
+	case "bool":
+		return pb.FieldType_FieldTypeBool, nil
+	case "text":
+		return pb.FieldType_FieldTypeText, nil
+	case "datetime":
+		return pb.FieldType_FieldTypeDatetime, nil
+	default:
+		return 0, fmt.Errorf("unsupported payload index schema %q", schema)
+	}
+}
+
+// pointID accepts the IDs the REST client sends: UUID strings and unsigned
+// integers (including the float64 form they take after a JSON round trip).
+func pointID(id any) (*pb.PointId, error) {
+	switch v := id.(type) {
+	case string:
+		return &pb.PointId{PointIdOptions: &pb.PointId_Uuid{Uuid: v}}, nil
+	case uint64:
+		return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: v}}, nil
+	case int:
+		if v >= 0 {
+			return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: uint64(v)}}, nil
+		}
+	case int64:
+		if v >= 0 {
+			return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: uint64(v)}}, nil
+		}
+	case float64:
+		if v >= 0 && v == math.Trunc(v) {
+			return &pb.PointId{PointIdOptions: &pb.PointId_Num{Num: uint64(v)}}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported point id %v (%T)", id, id)
+}
+
+// fromPointID mirrors what the REST client decodes: strings for UUIDs,
+// float64 for numeric IDs.
+func fromPointID(id *pb.PointId) any {
+	switch v := id.GetPointIdOptions().(type) {
+	case *pb.PointId_Uuid:
+		return v.Uuid
+	case *pb.PointId_Num:
+		return float64(v.Num)
+	}
+	return nil
+}
+
+func toPayload(p map[string]any) (map[string]*pb.Value, error) {
+	norm, err := NormalizePayload(p)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*pb.Value, len(norm))
+	for k, v := range norm {
+		out[k] = toValue(v)
+	}
+	return out, nil
+}
+
+// toValue converts a JSON value. Integral numbers become integers so that
+// integer payload indexes and match conditions see the same type as they
+// do over REST.
+func toValue(v any) *pb.Value {
+	switch x := v.(type) {
+	case nil:
+		return &pb.Value{Kind: &pb.Value_NullValue{}}
+	case bool:
+		return &pb.Value{Kind: &pb.Value_BoolValue{BoolValue: x}}
+	case string:
+		return &pb.Value{Kind: &pb.Value_StringValue{StringValue: x}}
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return &pb.Value{Kind: &pb.Value_IntegerValue{IntegerValue: int64(x)}}
+		}
+		return &pb.Value{Kind: &pb.Value_DoubleValue{DoubleValue: x}}
+	case []any:
+		vals := make([]*pb.Value, 0, len(x))
+		for _, e := range x {
+			vals = append(vals, toValue(e))
+		}
+		return &pb.Value{Kind: &pb.Value_ListValue{ListValue: &pb.ListValue{Values: vals}}}
+	case map[string]any:
+		fields := make(map[string]*pb.Value, len(x))
+		for k, e := range x {
+			fields[k] = toValue(e)
+		}
+		return &pb.Value{Kind: &pb.Value_StructValue{StructValue: &pb.Struct{Fields: fields}}}
+	}
+	return &pb.Value{Kind: &pb.Value_StringValue{StringValue: fmt.Sprint(v)}}
+}
+
+func fromPayload(p map[string]*pb.Value) map[string]any {
+	out := make(map[string]any, len(p))
+	for k, v := range p {
+		out[k] = fromValue(v)
+	}
+	return out
+}
+
+// fromValue returns the JSON types the REST client decodes, so callers
+// cannot tell the transports apart.
+func fromValue(v *pb.Value) any {
+	switch x := v.GetKind().(type) {
+	case *pb.Value_BoolValue:
+		return x.BoolValue
+	case *pb.Value_StringValue:
+		return x.StringValue
+	case *pb.Value_IntegerValue:
+		return float64(x.IntegerValue)
+	case *pb.Value_DoubleValue:
+		return x.DoubleValue
+	case *pb.Value_ListValue:
+		out := make([]any, 0, len(x.ListValue.GetValues()))
+		for _, e := range x.ListValue.GetValues() {
+			out = append(out, fromValue(e))
+		}
+		return out
+	case *pb.Value_StructValue:
+		return fromPayload(x.StructValue.GetFields())
+	}
+	return nil
+}
+
+// toFilter converts the JSON filter subset the gateway emits (the same one
+// Matcher evaluates) to protobuf. A nil or empty filter maps to nil.
+func toFilter(f Filter) (*pb.Filter, error) {
+	if len(f) == 0 {
+		return nil, nil
+	}
+	norm := map[string]any{}
+	if err := roundTrip(map[string]any(f), &norm); err != nil {
+		return nil, err
+	}
+	return filterFromJSON(norm)
+}
+
+func filterFromJSON(f map[string]any) (*pb.Filter, error) {
+	out := &pb.Filter{}
+	for k, v := range f {
+		list, ok := v.([]any)
+		if !ok && v != nil {
+			return nil, fmt.Errorf("filter clause %q must be a list", k)
+		}
+		conds := make([]*pb.Condition, 0, len(list))
+		for _, c := range list {
+			pc, err := conditionFromJSON(c)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, pc)
+		}
+		switch k {
+		case "must":
+			out.Must = conds
+		case "should":
+			out.Should = conds
+		case "must_not":
+			out.MustNot = conds
+		default:
+			return nil, fmt.Errorf("unsupported filter clause %q", k)
+		}
+	}
+	return out, nil
+}
+
+func conditionFromJSON(c any) (*pb.Condition, error) {
+	m, ok := c.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("filter condition must be an object, got %T", c)
+	}
+	if ids, ok := m["has_id"]; ok {
+		list, _ := ids.([]any)
+		pids := make([]*pb.PointId, 0, len(list))
+		for _, id := range list {
+			pid, err := pointID(id)
+			if err != nil {
+				return nil, err
+			}
+			pids = append(pids, pid)
+		}
+		return &pb.Condition{ConditionOneOf: &pb.Condition_HasId{HasId: &pb.HasIdCondition{HasId: pids}}}, nil
+	}
+	key, hasKey := m["key"].(string)
+	if !hasKey {
+		nested, err := filterFromJSON(m)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Condition{ConditionOneOf: &pb.Condition_Filter{Filter: nested}}, nil
+	}
+	fc := &pb.FieldCondition{Key: key}
+	switch {
+	case m["match"] != nil:
+		match, err := matchFromJSON(m["match"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		fc.Match = match
+	case m["range"] != nil:
+		r, err := rangeFromJSON(m["range"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		fc.Range = r
+	default:
+		return nil, fmt.Errorf("%s: unsupported field condition", key)
+	}
+	return &pb.Condition{ConditionOneOf: &pb.Condition_Field{Field: fc}}, nil
+}
+
+func matchFromJSON(v any) (*pb.Match, error) {
+	m, _ := v.(map[string]any)
+	if val, ok := m["value"]; ok {
+		switch x := val.(type) {
+		case string:
+			return &pb.Match{MatchValue: &pb.Match_Keyword{Keyword: x}}, nil
+		case bool:
+			return &pb.Match{MatchValue: &pb.Match_Boolean{Boolean: x}}, nil
+		case float64:
+			if x == math.Trunc(x) {
+				return &pb.Match{MatchValue: &pb.Match_Integer{Integer: int64(x)}}, nil
+			}
+		}
+		return nil, fmt.Errorf("unsupported match value %v", val)
+	}
+	anyList, ok := m["any"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported match %v", v)
+	}
+	if len(anyList) == 0 {
+		return &pb.Match{MatchValue: &pb.Match_Keywords{Keywords: &pb.RepeatedStrings{}}}, nil
+	}
+	switch anyList[0].(type) {
+	case string:
+		strs := make([]string, 0, len(anyList))
+		for _, e := range anyList {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("mixed match any values %v", anyList)
+			}
+			strs = append(strs, s)
+		}
+		return &pb.Match{MatchValue: &pb.Match_Keywords{Keywords: &pb.RepeatedStrings{Strings: strs}}}, nil
+	case float64:
+		ints := make([]int64, 0, len(anyList))
+		for _, e := range anyList {
+			n, ok := e.(float64)
+			if !ok || n != math.Trunc(n) {
+				return nil, fmt.Errorf("unsupported match any values %v", anyList)
+			}
+			ints = append(ints, int64(n))
+		}
+		return &pb.Match{MatchValue: &pb.Match_Integers{Integers: &pb.RepeatedIntegers{Integers: ints}}}, nil
+	}
+	return nil, fmt.Errorf("unsupported match any values %v", anyList)
+}
+
+func rangeFromJSON(v any) (*pb.Range, error) {
+	m, _ := v.(map[string]any)
+	r := &pb.Range{}
+	for k, val := range m {
+		n, ok := val.(float64)
+		if !ok {
+			return nil, fmt.Errorf("range %s must be a number", k)
+		}
+		switch k {
+		case "gt":
+			r.Gt = ptr(n)
+		case "gte":
+			r.Gte = ptr(n)
+		case "lt":
+			r.Lt = ptr(n)
+		case "lte":
+			r.Lte = ptr(n)
+		default:
+			return nil, fmt.Errorf("unsupported range bound %q", k)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,317 @@
+package qdrant
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// aclLikeFilter has the shape of the filters the API layer builds.
+func aclLikeFilter() Filter {
+	return Filter{
+		"must": []any{
+			map[string]any{"key": "project_id", "match": map[string]any{"any": []string{"p1", "p2"}}},
+			map[string]any{"key": "is_active", "match": map[string]any{"value": true}},
+			map[string]any{"key": "meta.priority", "match": map[string]any{"value": 2}},
+			map[string]any{"key": "created_at", "range": map[string]any{"gte": 100}},
+			map[string]any{"should": []any{
+				map[string]any{"key": "acl_public", "match": map[string]any{"value": true}},
+				map[string]any{"key": "acl_allow", "match": map[string]any{"any": []string{"eng"}}},
+			}},
+		},
+		"must_not": []any{
+			map[string]any{"has_id": []any{"6f1c0e3a-1111-4c1e-9c57-5b8a0c2f0001", 7}},
+		},
+	}
+}
+
+func TestToFilter(t *testing.T) {
+	f, err := toFilter(aclLikeFilter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Must) != 5 || len(f.MustNot) != 1 || len(f.Should) != 0 {
+		t.Fatalf("unexpected clause sizes: %v", f)
+	}
+	if got := f.Must[0].GetField().GetMatch().GetKeywords().GetStrings(); !reflect.DeepEqual(got, []string{"p1", "p2"}) {
+		t.Fatalf("project_id match any = %v", got)
+	}
+	if !f.Must[1].GetField().GetMatch().GetBoolean() {
+		t.Fatalf("is_active should be a boolean match: %v", f.Must[1])
+	}
+	if got := f.Must[2].GetField().GetMatch().GetInteger(); got != 2 {
+		t.Fatalf("integral match should become an integer match, got %v", f.Must[2])
+	}
+	if got := f.Must[3].GetField().GetRange().GetGte(); got != 100 {
+		t.Fatalf("range gte = %v", got)
+	}
+	if nested := f.Must[4].GetFilter(); nested == nil || len(nested.Should) != 2 {
+		t.Fatalf("expected nested should filter, got %v", f.Must[4])
+	}
+	ids := f.MustNot[0].GetHasId().GetHasId()
+	if len(ids) != 2 || ids[0].GetUuid() == "" || ids[1].GetNum() != 7 {
+		t.Fatalf("unexpected has_id: %v", ids)
+	}
+
+	if f, err := toFilter(nil); err != nil || f != nil {
+		t.Fatalf("empty filter should map to nil, got %v %v", f, err)
+	}
+	bad := []Filter{
+		{"min_should": []any{}},
+		{"must": []any{map[string]any{"key": "x", "match": map[string]any{"value": 1.5}}}},
+		{"must": []any{map[string]any{"key": "x", "geo_radius": map[string]any{}}}},
+		{"must": []any{map[string]any{"key": "x", "match": map[string]any{"any": []any{"a", 1}}}}},
+	}
+	for _, b := range bad {
+		if _, err := toFilter(b); err == nil {
+			t.Fatalf("expected %v to be rejected", b)
+		}
+	}
+}
+
+func TestPayloadRoundTrip(t *testing.T) {
+	in := map[string]any{
+		"doc_id":     "d1",
+		"chunk_id":   3,
+		"score":      0.5,
+		"is_active":  true,
+		"acl_allow":  []string{"eng", "ops"},
+		"meta":       map[string]any{"tags": []any{"a"}, "priority": 2},
+		"deleted_at": nil,
+	}
+	p, err := toPayload(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p["chunk_id"].GetKind().(*pb.Value_IntegerValue); !ok {
+		t.Fatalf("integral numbers should be sent as integers, got %v", p["chunk_id"])
+	}
+	want, _ := NormalizePayload(in)
+	if got := fromPayload(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestPointID(t *testing.T) {
+	for _, id := range []any{"6f1c0e3a-1111-4c1e-9c57-5b8a0c2f0001", 5, int64(5), uint64(5), float64(5)} {
+		pid, err := pointID(id)
+		if err != nil {
+			t.Fatalf("%v: %v", id, err)
+		}
+		back := fromPointID(pid)
+		if s, ok := id.(string); ok {
+			if back != s {
+				t.Fatalf("uuid round trip: %v", back)
+			}
+		} else if back != float64(5) {
+			t.Fatalf("numeric ids decode as float64 like REST, got %v (%T)", back, back)
+		}
+	}
+	for _, id := range []any{-1, 1.5, true} {
+		if _, err := pointID(id); err == nil {
+			t.Fatalf("expected %v to be rejected", id)
+		}
+	}
+}
+
+// fakeQdrant implements just enough of the gRPC services to check that
+// GRPCClient sends and decodes what the REST client would.
+type fakeQdrant struct {
+	pb.UnimplementedPointsServer
+
+	mu          sync.Mutex
+	collections map[string]bool
+	points      []*pb.PointStruct
+	lastFilter  *pb.Filter
+}
+
+type fakeCollections struct {
+	pb.UnimplementedCollectionsServer
+	*fakeQdrant
+}
+
+func (f fakeCollections) Create(ctx context.Context, req *pb.CreateCollection) (*pb.CollectionOperationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.collections[req.CollectionName] {
+		return nil, status.Error(codes.AlreadyExists, "exists")
+	}
+	f.collections[req.CollectionName] = true
+	return &pb.CollectionOperationResponse{Result: true}, nil
+}
+
+func (f *fakeQdrant) Upsert(ctx context.Context, req *pb.UpsertPoints) (*pb.PointsOperationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.points = append(f.points, req.Points...)
+	return &pb.PointsOperationResponse{}, nil
+}
+
+func (f *fakeQdrant) Search(ctx context.Context, req *pb.SearchPoints) (*pb.SearchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastFilter = req.Filter
+	out := &pb.SearchResponse{}
+	for _, p := range f.points {
+		out.Result = append(out.Result, &pb.ScoredPoint{Id: p.Id, Payload: p.Payload, Score: 0.75})
+	}
+	return out, nil
+}
+
+func startFakeQdrant(t *testing.T) (*fakeQdrant, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	fake := &fakeQdrant{collections: map[string]bool{}}
+	srv := grpc.NewServer()
+	pb.RegisterPointsServer(srv, fake)
+	pb.RegisterCollectionsServer(srv, fakeCollections{fakeQdrant: fake})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return fake, lis.Addr().String()
+}
+
+func TestGRPCClient_AgainstFake(t *testing.T) {
+	fake, addr := startFakeQdrant(t)
+	c, err := NewGRPC(addr, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := c.EnsureCollection(ctx, "kb", 3); err != nil {
+			t.Fatalf("ensure collection (attempt %d): %v", i+1, err)
+		}
+	}
+	payload := map[string]any{"doc_id": "d1", "chunk_id": 0, "acl_allow": []string{"eng"}}
+	id := "6f1c0e3a-1111-4c1e-9c57-5b8a0c2f0001"
+	if err := c.Upsert(ctx, "kb", []Point{{ID: id, Vector: []float32{1, 0, 0}, Payload: payload}}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Search(ctx, "kb", []float32{1, 0, 0}, aclLikeFilter(), 5, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.lastFilter == nil || len(fake.lastFilter.Must) != 5 {
+		t.Fatalf("filter not sent: %v", fake.lastFilter)
+	}
+	want, _ := NormalizePayload(payload)
+	if len(res) != 1 || res[0].ID != id || res[0].Score != 0.75 || !reflect.DeepEqual(res[0].Payload, want) {
+		t.Fatalf("unexpected search result: %+v", res)
+	}
+	if _, err := c.Count(ctx, "kb", nil); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected server errors to be wrapped, got %v", err)
+	}
+}
+
+// benchSearch is a typical gateway search: a 384-dim query with the ACL filter.
+func benchSearch() ([]float32, Filter) {
+	vec := make([]float32, 384)
+	for i := range vec {
+		vec[i] = float32(i%7) / 7
+	}
+	return vec, aclLikeFilter()
+}
+
+// The encode benchmarks compare request serialization cost without a server.
+func BenchmarkEncodeSearch_JSON(b *testing.B) {
+	vec, f := benchSearch()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(map[string]any{"vector": vec, "limit": 10, "with_payload": true, "filter": f}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeSearch_Protobuf(b *testing.B) {
+	vec, f := benchSearch()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pf, err := toFilter(f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := proto.Marshal(&pb.SearchPoints{CollectionName: "kb", Vector: vec, Filter: pf, Limit: 10}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSearchTransport compares both transports against a live Qdrant.
+// Set KBG_BENCH_QDRANT_URL and KBG_BENCH_QDRANT_GRPC_ADDR (e.g.
+// http://localhost:6333 and localhost:6334 from docker-compose) to run it.
+func BenchmarkSearchTransport(b *testing.B) {
+	url, addr := os.Getenv("KBG_BENCH_QDRANT_URL"), os.Getenv("KBG_BENCH_QDRANT_GRPC_ADDR")
+	if url == "" || addr == "" {
+		b.Skip("KBG_BENCH_QDRANT_URL and KBG_BENCH_QDRANT_GRPC_ADDR not set")
+	}
+	g, err := NewGRPC(addr, 10*time.Second)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer g.Close()
+	transports := []struct {
+		name string
+		c    interface {
+			Search(context.Context, string, []float32, Filter, int, SearchOptions) ([]SearchResult, error)
+		}
+	}{
+		{"http", New(url, 10*time.Second)},
+		{"grpc", g},
+	}
+
+	ctx := context.Background()
+	const collection = "kbg_bench"
+	vec, f := benchSearch()
+	h := New(url, 10*time.Second)
+	if err := h.EnsureCollection(ctx, collection, len(vec)); err != nil {
+		b.Fatal(err)
+	}
+	points := make([]Point, 0, 256)
+	for i := 0; i < 256; i++ {
+		v := append([]float32(nil), vec...)
+		v[i%len(v)] += 1
+		points = append(points, Point{ID: i + 1, Vector: v, Payload: map[string]any{
+			"project_id": "p1", "is_active": true, "created_at": 200, "acl_public": true, "meta": map[string]any{"priority": 2},
+		}})
+	}
+	if err := h.Upsert(ctx, collection, points); err != nil {
+		b.Fatal(err)
+	}
+	defer deleteBenchCollection(url, collection)
+
+	for _, tr := range transports {
+		b.Run(tr.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := tr.c.Search(ctx, collection, vec, f, 10, SearchOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func deleteBenchCollection(url, collection string) {
+	req, _ := http.NewRequest(http.MethodDelete, url+"/collections/"+collection, nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,143 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls retries of idempotent calls and the circuit breaker
+// shared by all calls of one client. Only ErrUnavailable failures are
+// retried or counted by the breaker.
+type RetryPolicy struct {
+	MaxRetries int
+	// Backoff is the base delay; it doubles per retry up to MaxBackoff and
+	// each wait is drawn uniformly from [0, delay) ("full jitter").
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BreakerThreshold consecutive failures open the breaker for
+	// BreakerCooldown, after which a single probe call is let through.
+	// Zero disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:       3,
+	Backoff:          100 * time.Millisecond,
+	MaxBackoff:       2 * time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  10 * time.Second,
+}
+
+type guard struct {
+	policy  RetryPolicy
+	breaker *breaker
+	sleep   func(context.Context, time.Duration) error
+}
+
+func newGuard(p RetryPolicy) *guard {
+	return &guard{
+		policy:  p,
+		breaker: &breaker{threshold: p.BreakerThreshold, cooldown: p.BreakerCooldown, now: time.Now},
+		sleep:   sleepCtx,
+	}
+}
+
+// run calls fn, retrying unavailable errors when the call is idempotent.
+// It gives up early when ctx is done or the breaker opens.
+func (g *guard) run(ctx context.Context, idempotent bool, fn func(context.Context) error) error {
+	attempts := 1
+	if idempotent {
+		attempts += g.policy.MaxRetries
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if g.sleep(ctx, g.backoff(i)) != nil {
+				return err
+			}
+		}
+		if !g.breaker.allow() {
+			return ErrCircuitOpen
+		}
+		err = fn(ctx)
+		unavailable := errors.Is(err, ErrUnavailable)
+		g.breaker.record(unavailable)
+		if !unavailable || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (g *guard) backoff(retry int) time.Duration {
+	d := g.policy.Backoff << (retry - 1)
+	if d <= 0 || (g.policy.MaxBackoff > 0 && d > g.policy.MaxBackoff) {
+		d = g.policy.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// breaker is a consecutive-failure circuit breaker. Once open it rejects
+// calls until the cooldown passes, then admits one probe: success closes
+// it, failure reopens it for another cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,155 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestClient_RetriesUnavailable(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"result":{"count":7}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, time.Second).WithRetryPolicy(fastRetry)
+	n, err := c.Count(context.Background(), "kb", nil)
+	if err != nil || n != 7 {
+		t.Fatalf("expected success after retries, got %d %v", n, err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	cases := []struct {
+		status int
+		kind   error
+		calls  int32
+	}{
+		{http.StatusNotFound, ErrNotFound, 1},
+		{http.StatusConflict, ErrConflict, 1},
+		{http.StatusBadRequest, ErrBadRequest, 1},
+		{http.StatusServiceUnavailable, ErrUnavailable, 4},
+		{http.StatusInternalServerError, nil, 1},
+	}
+	for _, tc := range cases {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.Error(w, `{"status":{"error":"secret internals"}}`, tc.status)
+		}))
+		c := New(srv.URL, time.Second).WithRetryPolicy(fastRetry)
+		_, err := c.Count(context.Background(), "kb", nil)
+		srv.Close()
+
+		var qe *Error
+		if !errors.As(err, &qe) || qe.Status != tc.status {
+			t.Fatalf("%d: expected *Error with status, got %v", tc.status, err)
+		}
+		if tc.kind != nil && !errors.Is(err, tc.kind) {
+			t.Fatalf("%d: expected %v, got %v", tc.status, tc.kind, err)
+		}
+		if calls != tc.calls {
+			t.Fatalf("%d: expected %d attempts, got %d", tc.status, tc.calls, calls)
+		}
+	}
+
+	c := New("http://127.0.0.1:1", time.Second).WithRetryPolicy(RetryPolicy{})
+	if _, err := c.Count(context.Background(), "kb", nil); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("connection errors should be unavailable, got %v", err)
+	}
+}
+
+func TestClient_EnsureCollectionConflictIsOK(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "exists", http.StatusConflict)
+	}))
+	defer srv.Close()
+	if err := New(srv.URL, time.Second).EnsureCollection(context.Background(), "kb", 3); err != nil {
+		t.Fatalf("existing collection should not be an error: %v", err)
+	}
+}
+
+func TestBreaker_OpensAndProbes(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := newGuard(RetryPolicy{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	g.breaker.now = func() time.Time { return now }
+
+	var calls int
+	down := func(context.Context) error { calls++; return &Error{Op: "test", Kind: ErrUnavailable} }
+	up := func(context.Context) error { calls++; return nil }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := g.run(ctx, true, down); !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("attempt %d should reach qdrant: %v", i, err)
+		}
+	}
+	if err := g.run(ctx, true, up); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("expected fast fail while open, got %v after %d calls", err, calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := g.run(ctx, true, down); errors.Is(err, ErrCircuitOpen) || calls != 3 {
+		t.Fatalf("expected a probe after cooldown, got %v after %d calls", err, calls)
+	}
+	if err := g.run(ctx, true, up); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("failed probe should reopen the breaker, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := g.run(ctx, true, up); err != nil {
+		t.Fatalf("successful probe should pass: %v", err)
+	}
+	if err := g.run(ctx, true, up); err != nil || calls != 5 {
+		t.Fatalf("breaker should be closed again, got %v after %d calls", err, calls)
+	}
+}
+
+func TestGuard_NonIdempotentIsNotRetried(t *testing.T) {
+	g := newGuard(fastRetry)
+	var calls int
+	err := g.run(context.Background(), false, func(context.Context) error {
+		calls++
+		return &Error{Op: "test", Kind: ErrUnavailable}
+	})
+	if !errors.Is(err, ErrUnavailable) || calls != 1 {
+		t.Fatalf("expected one attempt, got %d (%v)", calls, err)
+	}
+}
+
+func TestClient_CreateSnapshotIsNotRetried(t *testing.T) {
+	var creates, lists int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&creates, 1)
+		} else if atomic.AddInt32(&lists, 1) > 1 {
+			w.Write([]byte(`{"result":[{"name":"kb-1.snapshot","creation_time":"2026-10-19T10:00:00","size":42}]}`))
+			return
+		}
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, time.Second).WithRetryPolicy(fastRetry)
+	if _, err := c.CreateSnapshot(context.Background(), "kb"); !errors.Is(err, ErrUnavailable) || creates != 1 {
+		t.Fatalf("expected one create attempt, got %d (%v)", creates, err)
+	}
+	list, err := c.ListSnapshots(context.Background(), "kb")
+	if err != nil || len(list) != 1 || list[0].Size != 42 || lists != 2 {
+		t.Fatalf("expected listing to be retried, got %+v %v after %d calls", list, err, lists)
+	}
+}
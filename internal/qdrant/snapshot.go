@@ -0,0 +1,75 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	pb "github.com/qdrant/go-client/qdrant"
+)
+
+// SnapshotInfo describes a collection snapshot stored on the Qdrant node.
+type SnapshotInfo struct {
+	Name         string `json:"name"`
+	CreationTime string `json:"creation_time,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// CreateSnapshot snapshots collection and waits for it to finish. It is not
+// retried: a timed-out attempt may still produce a snapshot.
+func (c *Client) CreateSnapshot(ctx context.Context, collection string) (SnapshotInfo, error) {
+	var out struct {
+		Result SnapshotInfo `json:"result"`
+	}
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf("/collections/%s/snapshots?wait=true", collection), nil, &out); err != nil {
+		return SnapshotInfo{}, err
+	}
+	return out.Result, nil
+}
+
+func (c *Client) ListSnapshots(ctx context.Context, collection string) ([]SnapshotInfo, error) {
+	var out struct {
+		Result []SnapshotInfo `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/collections/%s/snapshots", collection), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Result, nil
+}
+
+func (c *GRPCClient) CreateSnapshot(ctx context.Context, collection string) (SnapshotInfo, error) {
+	var resp *pb.CreateSnapshotResponse
+	err := c.callOnce(ctx, "create snapshot", func(ctx context.Context) (err error) {
+		resp, err = c.snapshots.Create(ctx, &pb.CreateSnapshotRequest{CollectionName: collection})
+		return err
+	})
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	return fromSnapshot(resp.GetSnapshotDescription()), nil
+}
+
+func (c *GRPCClient) ListSnapshots(ctx context.Context, collection string) ([]SnapshotInfo, error) {
+	var resp *pb.ListSnapshotsResponse
+	err := c.call(ctx, "list snapshots", func(ctx context.Context) (err error) {
+		resp, err = c.snapshots.List(ctx, &pb.ListSnapshotsRequest{CollectionName: collection})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SnapshotInfo, 0, len(resp.GetSnapshotDescriptions()))
+	for _, d := range resp.GetSnapshotDescriptions() {
+		out = append(out, fromSnapshot(d))
+	}
+	return out, nil
+}
+
+// fromSnapshot formats the creation time like the REST API does.
+func fromSnapshot(d *pb.SnapshotDescription) SnapshotInfo {
+	info := SnapshotInfo{Name: d.GetName(), Size: d.GetSize()}
+	if t := d.GetCreationTime(); t != nil {
+		info.CreationTime = t.AsTime().UTC().Format("2006-01-02T15:04:05")
+	}
+	return info
+}
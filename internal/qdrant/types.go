@@ -7,3 +7,9 @@ type Point struct {
 }
 
 type Filter map[string]any
+
+// CollectionInfo is the vector configuration of an existing collection.
+type CollectionInfo struct {
+	VectorSize int
+	Distance   string // Qdrant spelling: "Cosine", "Dot", "Euclid", "Manhattan"
+}
@@ -0,0 +1,190 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// Role is a per-project role. Roles are ordered: each one includes the
+// permissions of the roles before it.
+type Role string
+
+const (
+	RoleNone      Role = ""
+	RoleReader    Role = "reader"
+	RoleEditor    Role = "editor"
+	RolePublisher Role = "publisher"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleNone:      0,
+	RoleReader:    1,
+	RoleEditor:    2,
+	RolePublisher: 3,
+	RoleAdmin:     4,
+}
+
+// Includes reports whether r grants at least the permissions of min.
+func (r Role) Includes(min Role) bool { return roleRank[r] >= roleRank[min] }
+
+// AllProjects is the policy entry that applies to every project.
+const AllProjects = "*"
+
+type ProjectPolicy struct {
+	// Groups maps principal groups to roles.
+	Groups map[string]Role `json:"groups"`
+	// Principals maps principal IDs (e.g. service accounts) to roles.
+	Principals map[string]Role `json:"principals"`
+}
+
+// Policy maps project IDs (or "*") to role grants.
+type Policy struct {
+	Projects map[string]ProjectPolicy `json:"projects"`
+}
+
+func ParsePolicy(b []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	for project, pp := range p.Projects {
+		for g, r := range pp.Groups {
+			if _, ok := roleRank[r]; !ok || r == RoleNone {
+				return nil, fmt.Errorf("project %s group %s: unknown role %q", project, g, r)
+			}
+		}
+		for id, r := range pp.Principals {
+			if _, ok := roleRank[r]; !ok || r == RoleNone {
+				return nil, fmt.Errorf("project %s principal %s: unknown role %q", project, id, r)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// RoleFor returns the highest role pr holds on projectID, combining the
+// project's own entry with the "*" entry. Customer principals only get roles
+// from explicit per-project grants; "*" never applies to them.
+func (p *Policy) RoleFor(pr types.Principal, projectID string) Role {
+	best := RoleNone
+	keys := []string{projectID, AllProjects}
+	if pr.Type == types.PrincipalCustomerUser {
+		keys = keys[:1]
+	}
+	for _, key := range keys {
+		pp, ok := p.Projects[key]
+		if !ok {
+			continue
+		}
+		if r := pp.Principals[pr.ID]; pr.ID != "" && !best.Includes(r) {
+			best = r
+		}
+		for _, g := range pr.Groups {
+			if r := pp.Groups[g]; !best.Includes(r) {
+				best = r
+			}
+		}
+	}
+	return best
+}
+
+// Authorizer serves a Policy loaded from a JSON file and reloads it when the
+// file changes.
+type Authorizer struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+}
+
+func Load(path string) (*Authorizer, error) {
+	a := &Authorizer{path: path}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload re-reads the policy file. On error the previous policy stays active.
+func (a *Authorizer) Reload() error {
+	st, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+	p, err := ParsePolicy(b)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", a.path, err)
+	}
+	a.mu.Lock()
+	a.policy = p
+	a.modTime = st.ModTime()
+	a.mu.Unlock()
+	return nil
+}
+
+// Watch polls the policy file every interval and reloads it when its mtime
+// changes, until ctx is done.
+func (a *Authorizer) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		st, err := os.Stat(a.path)
+		if err != nil {
+			log.Printf("rbac: stat policy: %v", err)
+			continue
+		}
+		a.mu.RLock()
+		changed := !st.ModTime().Equal(a.modTime)
+		a.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := a.Reload(); err != nil {
+			log.Printf("rbac: reload policy failed, keeping previous: %v", err)
+			continue
+		}
+		log.Printf("rbac: reloaded policy from %s", a.path)
+	}
+}
+
+func (a *Authorizer) RoleFor(pr types.Principal, projectID string) Role {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.policy.RoleFor(pr, projectID)
+}
+
+// AllowedProjects returns the subset of projects pr may read, in request order.
+func (a *Authorizer) AllowedProjects(pr types.Principal, projects []string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]string, 0, len(projects))
+	for _, p := range projects {
+		if a.policy.RoleFor(pr, p).Includes(RoleReader) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Allowed reports whether pr holds at least min on projectID.
+func (a *Authorizer) Allowed(pr types.Principal, projectID string, min Role) bool {
+	return a.RoleFor(pr, projectID).Includes(min)
+}
@@ -0,0 +1,107 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+const testPolicy = `{
+  "projects": {
+    "proj1": {"groups": {"eng": "editor", "eng-leads": "publisher"}, "principals": {"svc-feeder": "editor"}},
+    "*": {"groups": {"kb-admins": "admin", "staff": "reader"}}
+  }
+}`
+
+func TestPolicy_RoleFor(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		pr      types.Principal
+		project string
+		want    Role
+	}{
+		{types.Principal{Groups: []string{"eng"}}, "proj1", RoleEditor},
+		{types.Principal{Groups: []string{"eng"}}, "proj2", RoleNone},
+		{types.Principal{Groups: []string{"eng", "eng-leads"}}, "proj1", RolePublisher},
+		{types.Principal{Groups: []string{"staff", "eng"}}, "proj2", RoleReader},
+		{types.Principal{Groups: []string{"kb-admins"}}, "proj1", RoleAdmin},
+		{types.Principal{ID: "svc-feeder"}, "proj1", RoleEditor},
+		{types.Principal{ID: "svc-feeder"}, "proj2", RoleNone},
+	}
+	for _, tc := range cases {
+		if got := p.RoleFor(tc.pr, tc.project); got != tc.want {
+			t.Fatalf("RoleFor(%+v, %s) = %q, want %q", tc.pr, tc.project, got, tc.want)
+		}
+	}
+	customer := types.Principal{Type: types.PrincipalCustomerUser, Groups: []string{"staff"}}
+	if got := p.RoleFor(customer, "proj1"); got != RoleNone {
+		t.Fatalf("customers must not inherit \"*\" grants, got %q", got)
+	}
+	if !RoleAdmin.Includes(RolePublisher) || RoleEditor.Includes(RolePublisher) || RoleNone.Includes(RoleReader) {
+		t.Fatalf("unexpected role ordering")
+	}
+}
+
+func TestAuthorizer_AllowedProjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	policy := `{"projects":{"proj1":{"groups":{"customer:acme":"reader"}},"*":{"groups":{"staff":"reader"}}}}`
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := types.Principal{Type: types.PrincipalCustomerUser, Groups: []string{"customer:acme", "staff"}}
+	if got := a.AllowedProjects(customer, []string{"proj1", "proj2"}); len(got) != 1 || got[0] != "proj1" {
+		t.Fatalf("customer should only keep explicitly granted projects, got %v", got)
+	}
+	internal := types.Principal{Type: types.PrincipalInternalUser, Groups: []string{"staff"}}
+	if got := a.AllowedProjects(internal, []string{"proj1", "proj2"}); len(got) != 2 {
+		t.Fatalf("internal staff should keep all projects, got %v", got)
+	}
+}
+
+func TestParsePolicy_RejectsUnknownRole(t *testing.T) {
+	if _, err := ParsePolicy([]byte(`{"projects":{"p":{"groups":{"g":"owner"}}}}`)); err == nil {
+		t.Fatalf("expected unknown role to be rejected")
+	}
+}
+
+func TestAuthorizer_ReloadKeepsPreviousOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(testPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng := types.Principal{Groups: []string{"eng"}}
+	if !a.Allowed(eng, "proj1", RoleEditor) {
+		t.Fatalf("expected editor on proj1")
+	}
+	if err := os.WriteFile(path, []byte(`{"projects":{"proj1":{"groups":{"eng":"reader"}}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Allowed(eng, "proj1", RoleEditor) {
+		t.Fatalf("expected reload to downgrade eng to reader")
+	}
+	if err := os.WriteFile(path, []byte(`not json`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reload(); err == nil {
+		t.Fatalf("expected parse error")
+	}
+	if !a.Allowed(eng, "proj1", RoleReader) {
+		t.Fatalf("expected previous policy to stay active")
+	}
+}
@@ -0,0 +1,257 @@
+// Package redact detects sensitive spans (PII, secrets) in chunk text at
+// ingest and masks them at query time depending on who is reading.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/pkg/types"
+)
+
+// PayloadKey is the chunk payload field holding detected spans.
+const PayloadKey = "redactions"
+
+// minFragment is the shortest cut-off piece of a match that is still tagged
+// at a chunk boundary.
+const minFragment = 3
+
+// Rule tags every match of Pattern. Principal types in VisibleTo see matches
+// unmasked (default: internal users only); LLM prompts never do.
+type Rule struct {
+	Name      string                `json:"name"`
+	Category  string                `json:"category"`
+	Pattern   string                `json:"pattern"`
+	VisibleTo []types.PrincipalType `json:"visible_to"`
+
+	re *regexp.Regexp
+}
+
+// Span is a tagged byte range of a chunk's text.
+type Span struct {
+	Rule  string `json:"rule"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// DefaultRules are used when no rules file is configured.
+var DefaultRules = []Rule{
+	{Name: "email", Category: "pii", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{Name: "aws_access_key", Category: "secret", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "private_key", Category: "secret", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{Name: "credential", Category: "secret", Pattern: `(?i)\b(?:api[_-]?key|secret|token|password|passwd)\b\s*[:=]\s*['"]?[^\s'"]{6,}`},
+	{Name: "bearer_token", Category: "secret", Pattern: `(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`},
+}
+
+type Redactor struct {
+	rules  []Rule
+	byName map[string]*Rule
+}
+
+// New compiles rules. Rule names must be unique.
+func New(rules []Rule) (*Redactor, error) {
+	r := &Redactor{byName: map[string]*Rule{}}
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("rule needs name and pattern")
+		}
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule %q", rule.Name)
+		}
+		seen[rule.Name] = true
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		rule.re = re
+		if rule.VisibleTo == nil {
+			rule.VisibleTo = []types.PrincipalType{types.PrincipalInternalUser}
+		}
+		r.rules = append(r.rules, rule)
+	}
+	for i := range r.rules {
+		r.byName[r.rules[i].Name] = &r.rules[i]
+	}
+	return r, nil
+}
+
+// Load reads {"rules": [...]} from a JSON file.
+func Load(path string) (*Redactor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return New(f.Rules)
+}
+
+// Detect returns every rule match in text, ordered by position.
+func (r *Redactor) Detect(text string) []Span {
+	if r == nil {
+		return nil
+	}
+	var out []Span
+	for _, rule := range r.rules {
+		for _, m := range rule.re.FindAllStringIndex(text, -1) {
+			out = append(out, Span{Rule: rule.Name, Start: m[0], End: m[1]})
+		}
+	}
+	sortSpans(out)
+	return out
+}
+
+// DetectChunks tags spans in each chunk of doc. Chunks may cut a match in
+// two; pieces of a document-level match found at a chunk's start or end are
+// tagged as well, so no fragment of a secret escapes masking.
+func (r *Redactor) DetectChunks(doc string, chunks []string) [][]Span {
+	type match struct{ rule, value string }
+	seen := map[match]bool{}
+	var matches []match
+	for _, sp := range r.Detect(doc) {
+		m := match{sp.Rule, doc[sp.Start:sp.End]}
+		if len(m.value) > minFragment && !seen[m] {
+			seen[m] = true
+			matches = append(matches, m)
+		}
+	}
+	out := make([][]Span, len(chunks))
+	for i, c := range chunks {
+		spans := r.Detect(c)
+		if len(c) >= minFragment {
+			head, tail := c[:minFragment], c[len(c)-minFragment:]
+			for _, m := range matches {
+				if strings.Contains(m.value, head) {
+					if n := longestPrefixSuffix(c, m.value); n >= minFragment {
+						spans = append(spans, Span{Rule: m.rule, Start: 0, End: n})
+					}
+				}
+				if strings.Contains(m.value, tail) {
+					if n := longestPrefixSuffix(m.value, c); n >= minFragment {
+						spans = append(spans, Span{Rule: m.rule, Start: len(c) - n, End: len(c)})
+					}
+				}
+			}
+		}
+		sortSpans(spans)
+		out[i] = spans
+	}
+	return out
+}
+
+// longestPrefixSuffix returns the length of the longest proper suffix of
+// head that is also a proper prefix of tail. Whole matches are left to Detect.
+func longestPrefixSuffix(tail, head string) int {
+	max := len(head) - 1
+	if len(tail)-1 < max {
+		max = len(tail) - 1
+	}
+	for n := max; n > 0; n-- {
+		if strings.HasSuffix(head, tail[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// Mask replaces the spans pt may not see with [REDACTED:<rule>]. A nil
+// Redactor masks nothing.
+func (r *Redactor) Mask(text string, spans []Span, pt types.PrincipalType) string {
+	return r.mask(text, spans, func(rule *Rule) bool {
+		for _, v := range rule.VisibleTo {
+			if v == pt {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// MaskAll masks every span; used for LLM prompts.
+func (r *Redactor) MaskAll(text string, spans []Span) string {
+	return r.mask(text, spans, func(*Rule) bool { return true })
+}
+
+func (r *Redactor) mask(text string, spans []Span, hide func(*Rule) bool) string {
+	if r == nil {
+		return text
+	}
+	var hidden []Span
+	for _, sp := range spans {
+		if sp.Start < 0 || sp.End > len(text) || sp.Start >= sp.End {
+			continue
+		}
+		// Spans of rules that no longer exist stay masked.
+		if rule, ok := r.byName[sp.Rule]; ok && !hide(rule) {
+			continue
+		}
+		hidden = append(hidden, sp)
+	}
+	if len(hidden) == 0 {
+		return text
+	}
+	sortSpans(hidden)
+	var b strings.Builder
+	pos := 0
+	for _, sp := range hidden {
+		if sp.End <= pos {
+			continue
+		}
+		if sp.Start >= pos {
+			b.WriteString(text[pos:sp.Start])
+			fmt.Fprintf(&b, "[REDACTED:%s]", sp.Rule)
+		}
+		pos = sp.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// Category returns the category of a rule, or "" if unknown.
+func (r *Redactor) Category(rule string) string {
+	if r == nil {
+		return ""
+	}
+	if x, ok := r.byName[rule]; ok {
+		return x.Category
+	}
+	return ""
+}
+
+// SpansFromPayload decodes the PayloadKey field of a chunk payload.
+func SpansFromPayload(v any) []Span {
+	items, _ := v.([]any)
+	out := make([]Span, 0, len(items))
+	for _, it := range items {
+		m, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		rule, _ := m["rule"].(string)
+		start, ok1 := m["start"].(float64)
+		end, ok2 := m["end"].(float64)
+		if !ok1 || !ok2 {
+			continue
+		}
+		out = append(out, Span{Rule: rule, Start: int(start), End: int(end)})
+	}
+	return out
+}
+
+func sortSpans(spans []Span) {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].Start != spans[j].Start {
+			return spans[i].Start < spans[j].Start
+		}
+		return spans[i].End > spans[j].End
+	})
+}
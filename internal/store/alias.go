@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// AliasStore is implemented by stores that can address a collection through
+// an alias and repoint the alias atomically. Every VectorStore method accepts
+// an alias wherever it takes a collection name.
+type AliasStore interface {
+	// ResolveAlias returns the collection alias points to, or "" if there is
+	// no such alias.
+	ResolveAlias(ctx context.Context, alias string) (string, error)
+	// SwitchAlias points alias at collection in one step, creating it if needed.
+	SwitchAlias(ctx context.Context, alias, collection string) error
+	// DeleteCollection drops a collection and any aliases pointing at it.
+	DeleteCollection(ctx context.Context, name string) error
+	// ListCollections returns the names of all collections, without aliases.
+	ListCollections(ctx context.Context) ([]string, error)
+}
+
+var (
+	_ AliasStore = (*qdrant.Client)(nil)
+	_ AliasStore = (*qdrant.GRPCClient)(nil)
+	_ AliasStore = (*Memory)(nil)
+	_ AliasStore = (*Bolt)(nil)
+)
+
+func (m *Memory) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.aliases[alias], nil
+}
+
+func (m *Memory) SwitchAlias(ctx context.Context, alias, collection string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.switchAliasLocked(alias, collection)
+}
+
+func (m *Memory) switchAliasLocked(alias, collection string) error {
+	if _, ok := m.collections[collection]; !ok {
+		return fmt.Errorf("memory store: collection %s: %w", collection, qdrant.ErrNotFound)
+	}
+	if _, ok := m.collections[alias]; ok {
+		return fmt.Errorf("memory store: alias %s clashes with a collection: %w", alias, qdrant.ErrConflict)
+	}
+	m.aliases[alias] = collection
+	return nil
+}
+
+func (m *Memory) DeleteCollection(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteCollectionLocked(name)
+	return nil
+}
+
+func (m *Memory) ListCollections(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.collections))
+	for name := range m.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *Memory) deleteCollectionLocked(name string) {
+	delete(m.collections, name)
+	for a, c := range m.aliases {
+		if c == name {
+			delete(m.aliases, a)
+		}
+	}
+}
+
+// resolve maps an alias to its collection; other names are returned as is.
+func (m *Memory) resolve(name string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if c, ok := m.aliases[name]; ok {
+		return c
+	}
+	return name
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// BatchOptions controls UpsertBatches. Zero values fall back to the defaults.
+type BatchOptions struct {
+	Size        int
+	Parallelism int
+}
+
+const (
+	defaultBatchSize        = 64
+	defaultBatchParallelism = 4
+)
+
+// BatchFailure is one batch that could not be written.
+type BatchFailure struct {
+	Batch  int
+	Points []qdrant.Point
+	Err    error
+}
+
+// BatchError reports the failed batches of an UpsertBatches call; every
+// other batch was written. It unwraps to the batch errors, so errors.Is
+// sees e.g. qdrant.ErrUnavailable.
+type BatchError struct {
+	Batches int
+	Failed  []BatchFailure
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d upsert batches failed, first: %v", len(e.Failed), e.Batches, e.Failed[0].Err)
+}
+
+func (e *BatchError) Unwrap() []error {
+	out := make([]error, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		out = append(out, f.Err)
+	}
+	return out
+}
+
+// FailedPoints returns the points of all failed batches, in batch order.
+func (e *BatchError) FailedPoints() []qdrant.Point {
+	var out []qdrant.Point
+	for _, f := range e.Failed {
+		out = append(out, f.Points...)
+	}
+	return out
+}
+
+// UpsertBatches splits points into batches of opts.Size and upserts them
+// with at most opts.Parallelism requests in flight. A failed batch does not
+// stop the others; the result is nil or a *BatchError.
+func UpsertBatches(ctx context.Context, st VectorStore, collection string, points []qdrant.Point, opts BatchOptions) error {
+	size, par := opts.Size, opts.Parallelism
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if par <= 0 {
+		par = defaultBatchParallelism
+	}
+
+	var batches [][]qdrant.Point
+	for start := 0; start < len(points); start += size {
+		batches = append(batches, points[start:min(start+size, len(points))])
+	}
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, par)
+	var wg sync.WaitGroup
+	for i, b := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, b []qdrant.Point) {
+			defer func() { <-sem; wg.Done() }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = st.Upsert(ctx, collection, b)
+		}(i, b)
+	}
+	wg.Wait()
+
+	be := &BatchError{Batches: len(batches)}
+	for i, err := range errs {
+		if err != nil {
+			be.Failed = append(be.Failed, BatchFailure{Batch: i, Points: batches[i], Err: err})
+		}
+	}
+	if len(be.Failed) == 0 {
+		return nil
+	}
+	return be
+}
+
+// UpsertWithRetry calls UpsertBatches and then re-sends only the batches
+// that failed with a transient error, up to retries more times. Bad requests
+// and an open circuit breaker are returned as they are; the client already
+// retried and backed off before reporting them.
+func UpsertWithRetry(ctx context.Context, st VectorStore, collection string, points []qdrant.Point, opts BatchOptions, retries int) error {
+	err := UpsertBatches(ctx, st, collection, points, opts)
+	for attempt := 0; attempt < retries && err != nil; attempt++ {
+		var be *BatchError
+		if !errors.As(err, &be) || ctx.Err() != nil {
+			return err
+		}
+		var resend []qdrant.Point
+		kept := &BatchError{Batches: be.Batches}
+		for _, f := range be.Failed {
+			if retryable(f.Err) {
+				resend = append(resend, f.Points...)
+			} else {
+				kept.Failed = append(kept.Failed, f)
+			}
+		}
+		if len(resend) == 0 {
+			return err
+		}
+		err = UpsertBatches(ctx, st, collection, resend, opts)
+		if errors.As(err, &be) {
+			kept.Failed = append(kept.Failed, be.Failed...)
+		} else if err != nil {
+			return err
+		}
+		if len(kept.Failed) > 0 {
+			err = kept
+		}
+	}
+	return err
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, qdrant.ErrUnavailable) && !errors.Is(err, qdrant.ErrCircuitOpen)
+}
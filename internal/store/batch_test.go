@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// flakyStore fails the first upsert of any batch that starts with a point
+// in failOnce, with kind or else qdrant.ErrUnavailable, and records every
+// upsert and the peak concurrency.
+type flakyStore struct {
+	*Memory
+	mu       sync.Mutex
+	failOnce map[string]bool
+	kind     error
+	calls    [][]string
+	inFlight int
+	peak     int
+}
+
+func (f *flakyStore) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	var ids []string
+	for _, p := range points {
+		ids = append(ids, fmt.Sprint(p.ID))
+	}
+	f.calls = append(f.calls, ids)
+	fail := f.failOnce[ids[0]]
+	delete(f.failOnce, ids[0])
+	f.mu.Unlock()
+	defer func() { f.mu.Lock(); f.inFlight--; f.mu.Unlock() }()
+
+	if fail {
+		kind := f.kind
+		if kind == nil {
+			kind = qdrant.ErrUnavailable
+		}
+		return &qdrant.Error{Op: "test", Kind: kind}
+	}
+	return f.Memory.Upsert(ctx, collection, points)
+}
+
+func batchPoints(n int) []qdrant.Point {
+	out := make([]qdrant.Point, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, qdrant.Point{ID: fmt.Sprintf("p%02d", i), Vector: []float32{1, float32(i)}, Payload: map[string]any{"n": i}})
+	}
+	return out
+}
+
+func TestUpsertBatches_ReportsFailedBatches(t *testing.T) {
+	ctx := context.Background()
+	st := &flakyStore{Memory: NewMemory(), failOnce: map[string]bool{"p03": true, "p09": true}}
+	if err := st.EnsureCollection(ctx, "c", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UpsertBatches(ctx, st, "c", batchPoints(10), BatchOptions{Size: 3, Parallelism: 2})
+	var be *BatchError
+	if !errors.As(err, &be) || be.Batches != 4 || len(be.Failed) != 2 {
+		t.Fatalf("expected 2 of 4 batches to fail, got %v", err)
+	}
+	if be.Failed[0].Batch != 1 || be.Failed[1].Batch != 3 || len(be.FailedPoints()) != 4 {
+		t.Fatalf("unexpected failures: %+v", be.Failed)
+	}
+	if !errors.Is(err, qdrant.ErrUnavailable) {
+		t.Fatalf("batch error should unwrap to the store error")
+	}
+	if st.peak > 2 {
+		t.Fatalf("parallelism exceeded: %d", st.peak)
+	}
+	if n, _ := st.Count(ctx, "c", nil); n != 6 {
+		t.Fatalf("successful batches should be written, got %d points", n)
+	}
+}
+
+func TestUpsertWithRetry_ResendsOnlyFailedBatches(t *testing.T) {
+	ctx := context.Background()
+	st := &flakyStore{Memory: NewMemory(), failOnce: map[string]bool{"p03": true}}
+	if err := st.EnsureCollection(ctx, "c", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertWithRetry(ctx, st, "c", batchPoints(10), BatchOptions{Size: 3, Parallelism: 4}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.calls) != 5 || fmt.Sprint(st.calls[4]) != "[p03 p04 p05]" {
+		t.Fatalf("expected 4 batches plus one retry of the failed one, got %v", st.calls)
+	}
+	if n, _ := st.Count(ctx, "c", nil); n != 10 {
+		t.Fatalf("expected all points after retry, got %d", n)
+	}
+}
+
+func TestUpsertWithRetry_DoesNotResendPermanentFailures(t *testing.T) {
+	ctx := context.Background()
+	for _, kind := range []error{qdrant.ErrBadRequest, qdrant.ErrCircuitOpen} {
+		st := &flakyStore{Memory: NewMemory(), failOnce: map[string]bool{"p03": true}, kind: kind}
+		if err := st.EnsureCollection(ctx, "c", 2); err != nil {
+			t.Fatal(err)
+		}
+		err := UpsertWithRetry(ctx, st, "c", batchPoints(10), BatchOptions{Size: 3, Parallelism: 4}, 2)
+		if !errors.Is(err, kind) {
+			t.Fatalf("%v: expected the batch error back, got %v", kind, err)
+		}
+		if len(st.calls) != 4 {
+			t.Fatalf("%v: the failed batch must not be re-sent, got %v", kind, st.calls)
+		}
+	}
+}
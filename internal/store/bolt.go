@@ -0,0 +1,323 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt is the embedded backend: points are persisted in a local bbolt file
+// and served from an in-process Memory index (exact cosine, brute force)
+// loaded at open. Writes are serialised and hit the file before the index,
+// so a crash never leaves the index ahead of disk.
+type Bolt struct {
+	db  *bolt.DB
+	mem *Memory
+	mu  sync.Mutex // serialises writers
+}
+
+var _ VectorStore = (*Bolt)(nil)
+
+var (
+	boltDimKey    = []byte("dim")
+	boltPointsKey = []byte("points")
+	// boltAliasesBucket maps alias to collection. The leading NUL keeps it
+	// out of the collection namespace.
+	boltAliasesBucket = []byte("\x00aliases")
+)
+
+type boltPoint struct {
+	ID      any            `json:"id"`
+	Vector  []float32      `json:"vector"`
+	Payload map[string]any `json:"payload"`
+}
+
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("embedded store %s: %w", path, err)
+	}
+	b := &Bolt{db: db, mem: NewMemory()}
+	if err := b.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+func (b *Bolt) load() error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if string(name) == string(boltAliasesBucket) {
+				return bucket.ForEach(func(k, v []byte) error {
+					b.mem.aliases[string(k)] = string(v)
+					return nil
+				})
+			}
+			dim, err := strconv.Atoi(string(bucket.Get(boltDimKey)))
+			if err != nil {
+				return fmt.Errorf("embedded store: collection %s: bad dim: %w", name, err)
+			}
+			c := &memCollection{dim: dim, points: map[string]*memPoint{}}
+			points := bucket.Bucket(boltPointsKey)
+			if points != nil {
+				err := points.ForEach(func(k, v []byte) error {
+					var p boltPoint
+					if err := json.Unmarshal(v, &p); err != nil {
+						return fmt.Errorf("embedded store: collection %s point %s: %w", name, k, err)
+					}
+					c.points[string(k)] = &memPoint{id: p.ID, vector: p.Vector, norm: vectorNorm(p.Vector), payload: p.Payload}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			b.mem.collections[string(name)] = c
+			return nil
+		})
+	})
+}
+
+func (b *Bolt) EnsureCollection(ctx context.Context, name string, vectorDim int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.mem.resolve(name) != name {
+		return nil
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(name)) != nil {
+			return nil
+		}
+		bucket, err := tx.CreateBucket([]byte(name))
+		if err != nil {
+			return err
+		}
+		if _, err := bucket.CreateBucket(boltPointsKey); err != nil {
+			return err
+		}
+		return bucket.Put(boltDimKey, []byte(strconv.Itoa(vectorDim)))
+	})
+	if err != nil {
+		return fmt.Errorf("embedded store: ensure collection %s: %w", name, err)
+	}
+	return b.mem.EnsureCollection(ctx, name, vectorDim)
+}
+
+func (b *Bolt) CollectionInfo(ctx context.Context, name string) (qdrant.CollectionInfo, error) {
+	return b.mem.CollectionInfo(ctx, name)
+}
+
+func (b *Bolt) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	return b.mem.CreatePayloadIndex(ctx, collection, field, schema)
+}
+
+func (b *Bolt) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	collection = b.mem.resolve(collection)
+	dim, err := b.dim(collection)
+	if err != nil {
+		return err
+	}
+	records := make([]boltPoint, 0, len(points))
+	for _, p := range points {
+		if len(p.Vector) != dim {
+			return fmt.Errorf("embedded store: point %v has dimension %d, collection %s expects %d", p.ID, len(p.Vector), collection, dim)
+		}
+		payload, err := qdrant.NormalizePayload(p.Payload)
+		if err != nil {
+			return err
+		}
+		records = append(records, boltPoint{ID: p.ID, Vector: p.Vector, Payload: payload})
+	}
+	if err := b.put(collection, records); err != nil {
+		return err
+	}
+	return b.mem.Upsert(ctx, collection, points)
+}
+
+func (b *Bolt) SetPayload(ctx context.Context, collection string, payload map[string]any, filter qdrant.Filter) error {
+	patch, err := qdrant.NormalizePayload(payload)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	collection = b.mem.resolve(collection)
+	matched, err := b.matched(collection, filter)
+	if err != nil {
+		return err
+	}
+	records := make([]boltPoint, 0, len(matched))
+	for _, p := range matched {
+		next := copyPayload(p.payload)
+		for k, v := range patch {
+			next[k] = v
+		}
+		records = append(records, boltPoint{ID: p.id, Vector: p.vector, Payload: next})
+	}
+	if err := b.put(collection, records); err != nil {
+		return err
+	}
+	return b.mem.SetPayload(ctx, collection, payload, filter)
+}
+
+func (b *Bolt) DeleteByFilter(ctx context.Context, collection string, filter qdrant.Filter) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	collection = b.mem.resolve(collection)
+	matched, err := b.matched(collection, filter)
+	if err != nil {
+		return err
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		points := tx.Bucket([]byte(collection)).Bucket(boltPointsKey)
+		for _, p := range matched {
+			if err := points.Delete([]byte(fmt.Sprint(p.id))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("embedded store: delete: %w", err)
+	}
+	return b.mem.DeleteByFilter(ctx, collection, filter)
+}
+
+func (b *Bolt) Search(ctx context.Context, collection string, vector []float32, filter qdrant.Filter, limit int, opts qdrant.SearchOptions) ([]qdrant.SearchResult, error) {
+	return b.mem.Search(ctx, collection, vector, filter, limit, opts)
+}
+
+func (b *Bolt) Scroll(ctx context.Context, collection string, filter qdrant.Filter, limit int, offset any, withVector bool) (qdrant.ScrollResult, error) {
+	return b.mem.Scroll(ctx, collection, filter, limit, offset, withVector)
+}
+
+func (b *Bolt) Count(ctx context.Context, collection string, filter qdrant.Filter) (int, error) {
+	return b.mem.Count(ctx, collection, filter)
+}
+
+func (b *Bolt) dim(collection string) (int, error) {
+	b.mem.mu.RLock()
+	defer b.mem.mu.RUnlock()
+	c, err := b.mem.collection(collection)
+	if err != nil {
+		return 0, err
+	}
+	return c.dim, nil
+}
+
+// matched snapshots the points matching filter. Callers hold b.mu, so the
+// set cannot change before the index is updated.
+func (b *Bolt) matched(collection string, filter qdrant.Filter) ([]memPoint, error) {
+	b.mem.mu.RLock()
+	defer b.mem.mu.RUnlock()
+	c, err := b.mem.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	ptrs, err := c.match(filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]memPoint, len(ptrs))
+	for i, p := range ptrs {
+		out[i] = *p
+	}
+	return out, nil
+}
+
+func (b *Bolt) put(collection string, records []boltPoint) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return fmt.Errorf("collection %s not found", collection)
+		}
+		points := bucket.Bucket(boltPointsKey)
+		for _, r := range records {
+			v, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if err := points.Put([]byte(fmt.Sprint(r.ID)), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("embedded store: %w", err)
+	}
+	return nil
+}
+
+func (b *Bolt) ResolveAlias(ctx context.Context, alias string) (string, error) {
+	return b.mem.ResolveAlias(ctx, alias)
+}
+
+func (b *Bolt) SwitchAlias(ctx context.Context, alias, collection string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	if _, ok := b.mem.collections[collection]; !ok {
+		return fmt.Errorf("embedded store: collection %s: %w", collection, qdrant.ErrNotFound)
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltAliasesBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(alias), []byte(collection))
+	})
+	if err != nil {
+		return fmt.Errorf("embedded store: switch alias %s: %w", alias, err)
+	}
+	return b.mem.switchAliasLocked(alias, collection)
+}
+
+func (b *Bolt) DeleteCollection(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mem.mu.Lock()
+	defer b.mem.mu.Unlock()
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(name)) != nil {
+			if err := tx.DeleteBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		aliases := tx.Bucket(boltAliasesBucket)
+		if aliases == nil {
+			return nil
+		}
+		for a, c := range b.mem.aliases {
+			if c == name {
+				if err := aliases.Delete([]byte(a)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("embedded store: delete collection %s: %w", name, err)
+	}
+	b.mem.deleteCollectionLocked(name)
+	return nil
+}
+
+func (b *Bolt) ListCollections(ctx context.Context) ([]string, error) {
+	return b.mem.ListCollections(ctx)
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+func openTestBolt(t *testing.T, path string) *Bolt {
+	t.Helper()
+	b, err := OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBolt(t *testing.T) {
+	run := func(name string, fn func(*testing.T, VectorStore, string)) {
+		t.Run(name, func(t *testing.T) {
+			b := openTestBolt(t, filepath.Join(t.TempDir(), "kbg.db"))
+			defer b.Close()
+			fn(t, b, "c")
+		})
+	}
+	run("SearchFilterAndOrdering", testSearchFilterAndOrdering)
+	run("ScrollSetPayloadDelete", testScrollSetPayloadDelete)
+	run("InTx", testInTx)
+	run("Aliases", testAliases)
+}
+
+func TestBolt_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kbg.db")
+	ctx := context.Background()
+	b := openTestBolt(t, path)
+	seedStore(t, b, "c")
+	odd := qdrant.Filter{"must": []any{map[string]any{"key": "tags", "match": map[string]any{"value": "t1"}}}}
+	if err := b.SetPayload(ctx, "c", map[string]any{"is_active": true}, odd); err != nil {
+		t.Fatal(err)
+	}
+	first := qdrant.Filter{"must": []any{map[string]any{"has_id": []any{"p0"}}}}
+	if err := b.DeleteByFilter(ctx, "c", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SwitchAlias(ctx, "live", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b = openTestBolt(t, path)
+	defer b.Close()
+	if n, _ := b.Count(ctx, "live", nil); n != 4 {
+		t.Fatalf("expected 4 points through the persisted alias after reopen, got %d", n)
+	}
+	active := qdrant.Filter{"must": []any{map[string]any{"key": "is_active", "match": map[string]any{"value": true}}}}
+	if n, _ := b.Count(ctx, "c", active); n != 2 {
+		t.Fatalf("expected payload update to persist, got %d active", n)
+	}
+	res, err := b.Search(ctx, "c", []float32{1, 3}, nil, 1, qdrant.SearchOptions{})
+	if err != nil || len(res) != 1 || res[0].ID != "p3" {
+		t.Fatalf("expected vectors to persist, got %+v %v", res, err)
+	}
+	if err := b.EnsureCollection(ctx, "c", 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := b.Count(ctx, "c", nil); n != 4 {
+		t.Fatalf("EnsureCollection must keep existing points, got %d", n)
+	}
+}
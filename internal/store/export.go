@@ -0,0 +1,146 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// ExportFormat identifies the NDJSON export layout: one header line, one
+// line per point and an end line with the point count, so a truncated
+// stream is detected on import.
+const ExportFormat = "kbg-export/1"
+
+// maxExportLine bounds one NDJSON line (a point with its vector and text).
+const maxExportLine = 16 << 20
+
+type ExportHeader struct {
+	Format     string    `json:"format"`
+	ProjectID  string    `json:"project_id"`
+	Collection string    `json:"collection"`
+	VectorSize int       `json:"vector_size"`
+	Distance   string    `json:"distance"`
+	ActiveOnly bool      `json:"active_only"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+type exportLine struct {
+	Type string `json:"type"` // header, point or end
+	*ExportHeader
+	ID      any            `json:"id,omitempty"`
+	Vector  []float32      `json:"vector,omitempty"`
+	Payload map[string]any `json:"payload,omitempty"`
+	Points  *int           `json:"points,omitempty"`
+}
+
+// Export writes hdr and every point of collection matching filter, with
+// vectors, to w as NDJSON. If w has a Flush method it is called after each
+// page. It returns the number of points written.
+func Export(ctx context.Context, st VectorStore, collection string, filter qdrant.Filter, hdr ExportHeader, w io.Writer) (int, error) {
+	hdr.Format = ExportFormat
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(exportLine{Type: "header", ExportHeader: &hdr}); err != nil {
+		return 0, err
+	}
+	flusher, _ := w.(interface{ Flush() })
+	n := 0
+	var offset any
+	for {
+		page, err := st.Scroll(ctx, collection, filter, 256, offset, true)
+		if err != nil {
+			return n, err
+		}
+		for _, p := range page.Points {
+			if err := enc.Encode(exportLine{Type: "point", ID: p.ID, Vector: p.Vector, Payload: p.Payload}); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if page.NextPageOffset == nil {
+			break
+		}
+		offset = page.NextPageOffset
+	}
+	return n, enc.Encode(exportLine{Type: "end", Points: &n})
+}
+
+// ImportReader reads an export written by Export.
+type ImportReader struct {
+	Header ExportHeader
+	sc     *bufio.Scanner
+	line   int
+	points int
+	done   bool
+}
+
+var errTruncatedExport = errors.New("export is truncated: no end line")
+
+// NewImportReader reads and checks the header line.
+func NewImportReader(r io.Reader) (*ImportReader, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxExportLine)
+	ir := &ImportReader{sc: sc}
+	l, err := ir.next()
+	if err != nil {
+		return nil, err
+	}
+	if l.Type != "header" || l.ExportHeader == nil || l.Format != ExportFormat {
+		return nil, fmt.Errorf("line 1: expected a %s header", ExportFormat)
+	}
+	ir.Header = *l.ExportHeader
+	return ir, nil
+}
+
+// Next returns up to n points, or io.EOF after the end line.
+func (ir *ImportReader) Next(n int) ([]qdrant.Point, error) {
+	var out []qdrant.Point
+	for len(out) < n && !ir.done {
+		l, err := ir.next()
+		if err != nil {
+			return out, err
+		}
+		switch l.Type {
+		case "point":
+			if l.ID == nil || len(l.Vector) == 0 {
+				return out, fmt.Errorf("line %d: point without id or vector", ir.line)
+			}
+			out = append(out, qdrant.Point{ID: l.ID, Vector: l.Vector, Payload: l.Payload})
+			ir.points++
+		case "end":
+			if l.Points == nil || *l.Points != ir.points {
+				return out, fmt.Errorf("line %d: end line does not match the %d points read", ir.line, ir.points)
+			}
+			ir.done = true
+		default:
+			return out, fmt.Errorf("line %d: unexpected %q line", ir.line, l.Type)
+		}
+	}
+	if len(out) == 0 && ir.done {
+		return nil, io.EOF
+	}
+	return out, nil
+}
+
+func (ir *ImportReader) next() (exportLine, error) {
+	var l exportLine
+	if !ir.sc.Scan() {
+		if err := ir.sc.Err(); err != nil {
+			return l, err
+		}
+		return l, errTruncatedExport
+	}
+	ir.line++
+	if err := json.Unmarshal(ir.sc.Bytes(), &l); err != nil {
+		return l, fmt.Errorf("line %d: %w", ir.line, err)
+	}
+	return l, nil
+}
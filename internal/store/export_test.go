@@ -0,0 +1,65 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemory()
+	seedStore(t, src, "c")
+
+	var buf bytes.Buffer
+	n, err := Export(ctx, src, "c", nil, ExportHeader{ProjectID: "p", VectorSize: 2}, &buf)
+	if err != nil || n != 5 {
+		t.Fatalf("export: %d points, %v", n, err)
+	}
+
+	ir, err := NewImportReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ir.Header.Format != ExportFormat || ir.Header.ProjectID != "p" || ir.Header.VectorSize != 2 {
+		t.Fatalf("unexpected header %+v", ir.Header)
+	}
+	dst := NewMemory()
+	if err := dst.EnsureCollection(ctx, "c", 2); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		points, err := ir.Next(2)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Upsert(ctx, "c", points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := dst.Search(ctx, "c", []float32{1, 3}, nil, 1, qdrant.SearchOptions{})
+	if err != nil || len(res) != 1 || res[0].ID != "p3" || res[0].Payload["meta"].(map[string]any)["lang"] != "en" {
+		t.Fatalf("expected vectors and payloads to survive, got %+v %v", res, err)
+	}
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	truncated := strings.Join(lines[:len(lines)-2], "")
+	ir, err = NewImportReader(strings.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ir.Next(100); !errors.Is(err, errTruncatedExport) {
+		t.Fatalf("expected a missing end line to be an error, got %v", err)
+	}
+	if _, err := NewImportReader(strings.NewReader(`{"type":"point","id":1}` + "\n")); err == nil {
+		t.Fatalf("expected an error without a header")
+	}
+}
@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// Memory is an in-process VectorStore with Qdrant's filter semantics and
+// cosine scoring. It keeps everything in RAM and is meant for tests and
+// local experiments.
+type Memory struct {
+	mu          sync.RWMutex
+	collections map[string]*memCollection
+	aliases     map[string]string
+}
+
+type memCollection struct {
+	dim    int
+	points map[string]*memPoint
+}
+
+type memPoint struct {
+	id      any
+	vector  []float32
+	norm    float64
+	payload map[string]any
+}
+
+func NewMemory() *Memory {
+	return &Memory{collections: map[string]*memCollection{}, aliases: map[string]string{}}
+}
+
+var _ VectorStore = (*Memory)(nil)
+
+func (m *Memory) EnsureCollection(ctx context.Context, name string, vectorDim int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.aliases[name]; ok {
+		return nil
+	}
+	if _, ok := m.collections[name]; !ok {
+		m.collections[name] = &memCollection{dim: vectorDim, points: map[string]*memPoint{}}
+	}
+	return nil
+}
+
+// CollectionInfo reports Cosine: it is the only distance Search implements.
+func (m *Memory) CollectionInfo(ctx context.Context, name string) (qdrant.CollectionInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, err := m.collection(name)
+	if err != nil {
+		return qdrant.CollectionInfo{}, err
+	}
+	return qdrant.CollectionInfo{VectorSize: c.dim, Distance: "Cosine"}, nil
+}
+
+func (m *Memory) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, err := m.collection(collection)
+	return err
+}
+
+func (m *Memory) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	prepared := make([]*memPoint, 0, len(points))
+	for _, p := range points {
+		payload, err := qdrant.NormalizePayload(p.Payload)
+		if err != nil {
+			return err
+		}
+		prepared = append(prepared, &memPoint{
+			id:      p.ID,
+			vector:  append([]float32(nil), p.Vector...),
+			norm:    vectorNorm(p.Vector),
+			payload: payload,
+		})
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	for _, p := range prepared {
+		if len(p.vector) != c.dim {
+			return fmt.Errorf("memory store: point %v has dimension %d, collection %s expects %d", p.id, len(p.vector), collection, c.dim)
+		}
+	}
+	for _, p := range prepared {
+		c.points[fmt.Sprint(p.id)] = p
+	}
+	return nil
+}
+
+func (m *Memory) SetPayload(ctx context.Context, collection string, payload map[string]any, filter qdrant.Filter) error {
+	patch, err := qdrant.NormalizePayload(payload)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	matched, err := c.match(filter)
+	if err != nil {
+		return err
+	}
+	for _, p := range matched {
+		next := copyPayload(p.payload)
+		for k, v := range patch {
+			next[k] = v
+		}
+		p.payload = next
+	}
+	return nil
+}
+
+func (m *Memory) Search(ctx context.Context, collection string, vector []float32, filter qdrant.Filter, limit int, opts qdrant.SearchOptions) ([]qdrant.SearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(vector) != c.dim {
+		return nil, fmt.Errorf("memory store: query has dimension %d, collection %s expects %d", len(vector), collection, c.dim)
+	}
+	matched, err := c.match(filter)
+	if err != nil {
+		return nil, err
+	}
+	qnorm := vectorNorm(vector)
+	res := make([]qdrant.SearchResult, 0, len(matched))
+	for _, p := range matched {
+		score := cosine(vector, qnorm, p.vector, p.norm)
+		if opts.ScoreThreshold != nil && score < *opts.ScoreThreshold {
+			continue
+		}
+		res = append(res, qdrant.SearchResult{ID: p.id, Score: score, Payload: copyPayload(p.payload)})
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+	if opts.Offset >= len(res) {
+		return []qdrant.SearchResult{}, nil
+	}
+	res = res[opts.Offset:]
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// Scroll returns points ordered by ID; NextPageOffset is the ID of the first
+// point of the next page.
+func (m *Memory) Scroll(ctx context.Context, collection string, filter qdrant.Filter, limit int, offset any, withVector bool) (qdrant.ScrollResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return qdrant.ScrollResult{}, err
+	}
+	matched, err := c.match(filter)
+	if err != nil {
+		return qdrant.ScrollResult{}, err
+	}
+	start := 0
+	if offset != nil {
+		from := fmt.Sprint(offset)
+		start = sort.Search(len(matched), func(i int) bool { return fmt.Sprint(matched[i].id) >= from })
+	}
+	out := qdrant.ScrollResult{Points: []qdrant.ScoredPoint{}}
+	for i := start; i < len(matched); i++ {
+		if limit > 0 && len(out.Points) == limit {
+			out.NextPageOffset = matched[i].id
+			break
+		}
+		p := matched[i]
+		sp := qdrant.ScoredPoint{ID: p.id, Payload: copyPayload(p.payload)}
+		if withVector {
+			sp.Vector = append([]float32(nil), p.vector...)
+		}
+		out.Points = append(out.Points, sp)
+	}
+	return out, nil
+}
+
+func (m *Memory) Count(ctx context.Context, collection string, filter qdrant.Filter) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return 0, err
+	}
+	matched, err := c.match(filter)
+	return len(matched), err
+}
+
+func (m *Memory) DeleteByFilter(ctx context.Context, collection string, filter qdrant.Filter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, err := m.collection(collection)
+	if err != nil {
+		return err
+	}
+	matched, err := c.match(filter)
+	if err != nil {
+		return err
+	}
+	for _, p := range matched {
+		delete(c.points, fmt.Sprint(p.id))
+	}
+	return nil
+}
+
+func (m *Memory) collection(name string) (*memCollection, error) {
+	if target, ok := m.aliases[name]; ok {
+		name = target
+	}
+	c, ok := m.collections[name]
+	if !ok {
+		return nil, fmt.Errorf("memory store: collection %s: %w", name, qdrant.ErrNotFound)
+	}
+	return c, nil
+}
+
+// match returns the points matching f, ordered by ID.
+func (c *memCollection) match(f qdrant.Filter) ([]*memPoint, error) {
+	matcher, err := qdrant.NewMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(c.points))
+	for k := range c.points {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var out []*memPoint
+	for _, k := range keys {
+		p := c.points[k]
+		ok, err := matcher.Match(p.id, p.payload)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// copyPayload copies the top level; nested values are never mutated in place.
+func copyPayload(p map[string]any) map[string]any {
+	out := make(map[string]any, len(p))
+	for k, v := range p {
+		out[k] = v
+	}
+	return out
+}
+
+func vectorNorm(v []float32) float64 {
+	var s float64
+	for _, x := range v {
+		s += float64(x) * float64(x)
+	}
+	return math.Sqrt(s)
+}
+
+func cosine(a []float32, an float64, b []float32, bn float64) float64 {
+	if an == 0 || bn == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (an * bn)
+}
@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+func TestMemory(t *testing.T) {
+	run := func(name string, fn func(*testing.T, VectorStore, string)) {
+		t.Run(name, func(t *testing.T) { fn(t, NewMemory(), "c") })
+	}
+	run("SearchFilterAndOrdering", testSearchFilterAndOrdering)
+	run("ScrollSetPayloadDelete", testScrollSetPayloadDelete)
+	run("InTx", testInTx)
+	run("Aliases", testAliases)
+	if _, err := NewMemory().CollectionInfo(context.Background(), "missing"); !errors.Is(err, qdrant.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing collection, got %v", err)
+	}
+}
@@ -0,0 +1,444 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres stores points in a pgvector table named after the collection:
+//
+//	id text PRIMARY KEY, embedding vector(dim), payload jsonb
+//
+// The chunk payload is kept as JSONB with a jsonb_path_ops GIN index, and
+// qdrant.Filter conditions are translated to jsonpath predicates (payload @?
+// '...'), which that index serves. Search is an exact cosine scan, so filtered
+// results are never truncated by an approximate index.
+type Postgres struct {
+	pool *pgxpool.Pool
+	q    pgQuerier
+}
+
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+var (
+	_ VectorStore   = (*Postgres)(nil)
+	_ Transactional = (*Postgres)(nil)
+)
+
+func OpenPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("postgres: empty DSN")
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Postgres{pool: pool, q: pool}, nil
+}
+
+func (p *Postgres) Close() {
+	if p.pool != nil {
+		p.pool.Close()
+	}
+}
+
+// InTx runs fn against a view of the store bound to one transaction.
+func (p *Postgres) InTx(ctx context.Context, fn func(VectorStore) error) error {
+	if p.pool == nil {
+		// Already inside a transaction.
+		return fn(p)
+	}
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		return fn(&Postgres{q: tx})
+	})
+}
+
+func (p *Postgres) EnsureCollection(ctx context.Context, name string, vectorDim int) error {
+	t := table(name)
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id text PRIMARY KEY,
+			embedding vector(%d) NOT NULL,
+			payload jsonb NOT NULL DEFAULT '{}'
+		)`, t, vectorDim),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING gin (payload jsonb_path_ops)`, table(name+"_payload_idx"), t),
+	}
+	for _, s := range stmts {
+		if _, err := p.q.Exec(ctx, s); err != nil {
+			return fmt.Errorf("postgres: ensure collection %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CollectionInfo reads the dimension from the vector(n) column type. Search
+// always ranks by cosine distance.
+func (p *Postgres) CollectionInfo(ctx context.Context, name string) (qdrant.CollectionInfo, error) {
+	var dim int
+	err := p.q.QueryRow(ctx, `SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = to_regclass($1) AND attname = 'embedding'`, table(name)).Scan(&dim)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return qdrant.CollectionInfo{}, fmt.Errorf("postgres: collection %s: %w", name, qdrant.ErrNotFound)
+	}
+	if err != nil {
+		return qdrant.CollectionInfo{}, fmt.Errorf("postgres: collection %s: %w", name, err)
+	}
+	return qdrant.CollectionInfo{VectorSize: dim, Distance: "Cosine"}, nil
+}
+
+// CreatePayloadIndex is a no-op: the GIN index on payload covers every key.
+func (p *Postgres) CreatePayloadIndex(ctx context.Context, collection, field, schema string) error {
+	return nil
+}
+
+func (p *Postgres) Upsert(ctx context.Context, collection string, points []qdrant.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	sql := fmt.Sprintf(`INSERT INTO %s (id, embedding, payload) VALUES ($1, $2::vector, $3::jsonb)
+		ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding, payload = EXCLUDED.payload`, table(collection))
+	batch := &pgx.Batch{}
+	for _, pt := range points {
+		payload, err := json.Marshal(pt.Payload)
+		if err != nil {
+			return err
+		}
+		batch.Queue(sql, fmt.Sprint(pt.ID), vectorLiteral(pt.Vector), string(payload))
+	}
+	return p.InTx(ctx, func(st VectorStore) error {
+		return st.(*Postgres).q.SendBatch(ctx, batch).Close()
+	})
+}
+
+func (p *Postgres) SetPayload(ctx context.Context, collection string, payload map[string]any, filter qdrant.Filter) error {
+	patch, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	args := []any{string(patch)}
+	where, err := sqlFilter(filter, &args)
+	if err != nil {
+		return err
+	}
+	_, err = p.q.Exec(ctx, fmt.Sprintf(`UPDATE %s SET payload = payload || $1::jsonb WHERE %s`, table(collection), where), args...)
+	return err
+}
+
+func (p *Postgres) Search(ctx context.Context, collection string, vector []float32, filter qdrant.Filter, limit int, opts qdrant.SearchOptions) ([]qdrant.SearchResult, error) {
+	args := []any{vectorLiteral(vector)}
+	where, err := sqlFilter(filter, &args)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ScoreThreshold != nil {
+		args = append(args, *opts.ScoreThreshold)
+		where += fmt.Sprintf(` AND 1 - (embedding <=> $1::vector) >= $%d`, len(args))
+	}
+	args = append(args, limit, opts.Offset)
+	sql := fmt.Sprintf(`SELECT id, payload, 1 - (embedding <=> $1::vector) FROM %s WHERE %s
+		ORDER BY embedding <=> $1::vector, id LIMIT $%d OFFSET $%d`, table(collection), where, len(args)-1, len(args))
+	rows, err := p.q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []qdrant.SearchResult{}
+	for rows.Next() {
+		var r qdrant.SearchResult
+		var id string
+		if err := rows.Scan(&id, &r.Payload, &r.Score); err != nil {
+			return nil, err
+		}
+		r.ID = id
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// Scroll returns points ordered by id; NextPageOffset is the id of the first
+// point of the next page.
+func (p *Postgres) Scroll(ctx context.Context, collection string, filter qdrant.Filter, limit int, offset any, withVector bool) (qdrant.ScrollResult, error) {
+	var args []any
+	where, err := sqlFilter(filter, &args)
+	if err != nil {
+		return qdrant.ScrollResult{}, err
+	}
+	if offset != nil {
+		args = append(args, fmt.Sprint(offset))
+		where += fmt.Sprintf(` AND id >= $%d`, len(args))
+	}
+	cols := "id, payload"
+	if withVector {
+		cols += ", embedding::text"
+	}
+	sql := fmt.Sprintf(`SELECT %s FROM %s WHERE %s ORDER BY id`, cols, table(collection), where)
+	if limit > 0 {
+		args = append(args, limit+1)
+		sql += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := p.q.Query(ctx, sql, args...)
+	if err != nil {
+		return qdrant.ScrollResult{}, err
+	}
+	defer rows.Close()
+	out := qdrant.ScrollResult{Points: []qdrant.ScoredPoint{}}
+	for rows.Next() {
+		var id, vec string
+		var payload map[string]any
+		dest := []any{&id, &payload}
+		if withVector {
+			dest = append(dest, &vec)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return qdrant.ScrollResult{}, err
+		}
+		if limit > 0 && len(out.Points) == limit {
+			out.NextPageOffset = id
+			break
+		}
+		sp := qdrant.ScoredPoint{ID: id, Payload: payload}
+		if withVector {
+			if sp.Vector, err = parseVector(vec); err != nil {
+				return qdrant.ScrollResult{}, err
+			}
+		}
+		out.Points = append(out.Points, sp)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) Count(ctx context.Context, collection string, filter qdrant.Filter) (int, error) {
+	var args []any
+	where, err := sqlFilter(filter, &args)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = p.q.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s`, table(collection), where), args...).Scan(&n)
+	return n, err
+}
+
+func (p *Postgres) DeleteByFilter(ctx context.Context, collection string, filter qdrant.Filter) error {
+	var args []any
+	where, err := sqlFilter(filter, &args)
+	if err != nil {
+		return err
+	}
+	_, err = p.q.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, table(collection), where), args...)
+	return err
+}
+
+func table(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 32)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: parse vector: %w", err)
+		}
+		out[i] = float32(f)
+	}
+	return out, nil
+}
+
+// sqlFilter translates f into a WHERE expression, appending bind values to
+// args. It accepts the same subset as qdrant.Eval.
+func sqlFilter(f qdrant.Filter, args *[]any) (string, error) {
+	if len(f) == 0 {
+		return "TRUE", nil
+	}
+	var norm map[string]any
+	b, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(b, &norm); err != nil {
+		return "", err
+	}
+	return sqlClauses(norm, args)
+}
+
+func sqlClauses(f map[string]any, args *[]any) (string, error) {
+	var parts []string
+	for _, k := range []string{"must", "must_not", "should"} {
+		conds, _ := f[k].([]any)
+		var sqls []string
+		for _, c := range conds {
+			s, err := sqlCondition(c, args)
+			if err != nil {
+				return "", err
+			}
+			sqls = append(sqls, s)
+		}
+		switch {
+		case len(sqls) == 0:
+		case k == "must":
+			parts = append(parts, sqls...)
+		case k == "must_not":
+			for _, s := range sqls {
+				parts = append(parts, "NOT "+s)
+			}
+		default:
+			parts = append(parts, "("+strings.Join(sqls, " OR ")+")")
+		}
+	}
+	for k := range f {
+		if k != "must" && k != "should" && k != "must_not" {
+			return "", fmt.Errorf("unsupported filter clause %q", k)
+		}
+	}
+	if len(parts) == 0 {
+		return "TRUE", nil
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", nil
+}
+
+func sqlCondition(c any, args *[]any) (string, error) {
+	m, ok := c.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("unsupported condition %v", c)
+	}
+	if ids, ok := m["has_id"]; ok {
+		list, _ := ids.([]any)
+		if len(list) == 0 {
+			return "FALSE", nil
+		}
+		strs := make([]string, len(list))
+		for i, x := range list {
+			strs[i] = fmt.Sprint(x)
+		}
+		*args = append(*args, strs)
+		return fmt.Sprintf("id = ANY($%d::text[])", len(*args)), nil
+	}
+	key, ok := m["key"].(string)
+	if !ok {
+		return sqlClauses(m, args)
+	}
+	pred, err := jsonPathPredicate(m)
+	if err != nil {
+		return "", err
+	}
+	*args = append(*args, jsonPathKey(key)+" ? ("+pred+")")
+	return fmt.Sprintf("payload @? $%d::jsonpath", len(*args)), nil
+}
+
+// jsonPathKey builds a lax-mode path that, like Qdrant, looks through arrays
+// at every step of a dotted key.
+func jsonPathKey(key string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, part := range strings.Split(key, ".") {
+		q, _ := json.Marshal(part)
+		b.WriteString(".")
+		b.Write(q)
+		b.WriteString("[*]")
+	}
+	return b.String()
+}
+
+func jsonPathPredicate(m map[string]any) (string, error) {
+	if match, ok := m["match"].(map[string]any); ok {
+		if v, ok := match["value"]; ok {
+			lit, err := jsonPathLiteral(v)
+			if err != nil {
+				return "", err
+			}
+			return "@ == " + lit, nil
+		}
+		if list, ok := match["any"].([]any); ok {
+			if len(list) == 0 {
+				return "false", nil
+			}
+			ors := make([]string, len(list))
+			for i, v := range list {
+				lit, err := jsonPathLiteral(v)
+				if err != nil {
+					return "", err
+				}
+				ors[i] = "@ == " + lit
+			}
+			return strings.Join(ors, " || "), nil
+		}
+		return "", fmt.Errorf("unsupported match %v", match)
+	}
+	if r, ok := m["range"].(map[string]any); ok {
+		ops := map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+		var ands []string
+		for _, k := range sortedRangeKeys(r) {
+			op, ok := ops[k]
+			if !ok {
+				return "", fmt.Errorf("unsupported range bound %q", k)
+			}
+			n, ok := r[k].(float64)
+			if !ok {
+				return "", fmt.Errorf("range bound %q must be a number", k)
+			}
+			ands = append(ands, "@ "+op+" "+strconv.FormatFloat(n, 'f', -1, 64))
+		}
+		if len(ands) == 0 {
+			return "", fmt.Errorf("empty range")
+		}
+		return strings.Join(ands, " && "), nil
+	}
+	return "", fmt.Errorf("unsupported condition %v", m)
+}
+
+func jsonPathLiteral(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		b, _ := json.Marshal(x)
+		return string(b), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported match value %v", v)
+	}
+}
+
+func sortedRangeKeys(r map[string]any) []string {
+	keys := make([]string, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
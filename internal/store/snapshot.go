@@ -0,0 +1,20 @@
+package store
+
+import (
+	"context"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// Snapshotter is implemented by backends that keep server-side collection
+// snapshots (Qdrant). Other backends are backed up with Export or their own
+// tooling (pg_dump, copying the bbolt file).
+type Snapshotter interface {
+	CreateSnapshot(ctx context.Context, collection string) (qdrant.SnapshotInfo, error)
+	ListSnapshots(ctx context.Context, collection string) ([]qdrant.SnapshotInfo, error)
+}
+
+var (
+	_ Snapshotter = (*qdrant.Client)(nil)
+	_ Snapshotter = (*qdrant.GRPCClient)(nil)
+)
@@ -0,0 +1,41 @@
+// Package store defines the vector storage the gateway depends on. Filters
+// and points use the Qdrant JSON shapes from internal/qdrant regardless of
+// the backend.
+package store
+
+import (
+	"context"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+type VectorStore interface {
+	EnsureCollection(ctx context.Context, name string, vectorDim int) error
+	CollectionInfo(ctx context.Context, name string) (qdrant.CollectionInfo, error)
+	CreatePayloadIndex(ctx context.Context, collection, field, schema string) error
+	Upsert(ctx context.Context, collection string, points []qdrant.Point) error
+	SetPayload(ctx context.Context, collection string, payload map[string]any, filter qdrant.Filter) error
+	Search(ctx context.Context, collection string, vector []float32, filter qdrant.Filter, limit int, opts qdrant.SearchOptions) ([]qdrant.SearchResult, error)
+	Scroll(ctx context.Context, collection string, filter qdrant.Filter, limit int, offset any, withVector bool) (qdrant.ScrollResult, error)
+	Count(ctx context.Context, collection string, filter qdrant.Filter) (int, error)
+	DeleteByFilter(ctx context.Context, collection string, filter qdrant.Filter) error
+}
+
+var (
+	_ VectorStore = (*qdrant.Client)(nil)
+	_ VectorStore = (*qdrant.GRPCClient)(nil)
+)
+
+// Transactional is implemented by stores that can apply several writes
+// atomically, such as deactivating one version and activating another.
+type Transactional interface {
+	InTx(ctx context.Context, fn func(VectorStore) error) error
+}
+
+// InTx runs fn in a transaction when st supports one, otherwise directly.
+func InTx(ctx context.Context, st VectorStore, fn func(VectorStore) error) error {
+	if t, ok := st.(Transactional); ok {
+		return t.InTx(ctx, fn)
+	}
+	return fn(st)
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/HardMakabaka/KB-Gateway/internal/qdrant"
+)
+
+// seedStore creates collection c with five points p0..p4 in st.
+func seedStore(t *testing.T, st VectorStore, c string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := st.EnsureCollection(ctx, c, 2); err != nil {
+		t.Fatal(err)
+	}
+	var points []qdrant.Point
+	for i := 0; i < 5; i++ {
+		points = append(points, qdrant.Point{
+			ID:     fmt.Sprintf("p%d", i),
+			Vector: []float32{1, float32(i)},
+			Payload: map[string]any{
+				"n":    i,
+				"tags": []string{fmt.Sprintf("t%d", i%2)},
+				"meta": map[string]any{"lang": "en"},
+			},
+		})
+	}
+	if err := st.Upsert(ctx, c, points); err != nil {
+		t.Fatal(err)
+	}
+	info, err := st.CollectionInfo(ctx, c)
+	if err != nil || info.VectorSize != 2 || info.Distance != "Cosine" {
+		t.Fatalf("unexpected collection info %+v %v", info, err)
+	}
+}
+
+// The tests below take any VectorStore so every backend runs the same checks.
+
+func testSearchFilterAndOrdering(t *testing.T, m VectorStore, c string) {
+	seedStore(t, m, c)
+	ctx := context.Background()
+	f := qdrant.Filter{"must": []any{
+		map[string]any{"key": "tags", "match": map[string]any{"value": "t0"}},
+		map[string]any{"key": "meta.lang", "match": map[string]any{"any": []string{"en"}}},
+		map[string]any{"key": "n", "range": map[string]any{"gte": int64(1)}},
+	}}
+	res, err := m.Search(ctx, c, []float32{1, 0}, f, 10, qdrant.SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].ID != "p2" || res[1].ID != "p4" {
+		t.Fatalf("expected p2, p4 by descending cosine, got %+v", res)
+	}
+	threshold := 0.9
+	res, _ = m.Search(ctx, c, []float32{1, 0}, nil, 10, qdrant.SearchOptions{Offset: 1, ScoreThreshold: &threshold})
+	if len(res) != 0 {
+		t.Fatalf("offset past the only hit above threshold, got %+v", res)
+	}
+	if _, err := m.Search(ctx, c, []float32{1, 0}, qdrant.Filter{"must": []any{map[string]any{"key": "n", "geo_radius": 1}}}, 10, qdrant.SearchOptions{}); err == nil {
+		t.Fatalf("unsupported conditions must fail instead of matching")
+	}
+}
+
+func testScrollSetPayloadDelete(t *testing.T, m VectorStore, c string) {
+	seedStore(t, m, c)
+	ctx := context.Background()
+	var ids []any
+	var offset any
+	for {
+		page, err := m.Scroll(ctx, c, nil, 2, offset, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page.Points {
+			ids = append(ids, p.ID)
+		}
+		if page.NextPageOffset == nil {
+			break
+		}
+		offset = page.NextPageOffset
+	}
+	if len(ids) != 5 || ids[0] != "p0" || ids[4] != "p4" {
+		t.Fatalf("scroll visited %v", ids)
+	}
+
+	odd := qdrant.Filter{"must": []any{map[string]any{"key": "tags", "match": map[string]any{"value": "t1"}}}}
+	if err := m.SetPayload(ctx, c, map[string]any{"flag": true}, odd); err != nil {
+		t.Fatal(err)
+	}
+	flagged := qdrant.Filter{"must": []any{map[string]any{"key": "flag", "match": map[string]any{"value": true}}}}
+	if n, _ := m.Count(ctx, c, flagged); n != 2 {
+		t.Fatalf("expected 2 flagged points, got %d", n)
+	}
+	if err := m.DeleteByFilter(ctx, c, flagged); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.Count(ctx, c, nil); n != 3 {
+		t.Fatalf("expected 3 points after delete, got %d", n)
+	}
+	if err := m.Upsert(ctx, c, []qdrant.Point{{ID: "bad", Vector: []float32{1}}}); err == nil {
+		t.Fatalf("expected dimension mismatch error")
+	}
+	if err := m.Upsert(ctx, "missing", []qdrant.Point{{ID: "x", Vector: []float32{1, 0}}}); err == nil {
+		t.Fatalf("expected unknown collection error")
+	}
+}
+
+func testInTx(t *testing.T, st VectorStore, c string) {
+	seedStore(t, st, c)
+	ctx := context.Background()
+	all := qdrant.Filter{}
+	flagged := qdrant.Filter{"must": []any{map[string]any{"key": "flag", "match": map[string]any{"value": true}}}}
+	err := InTx(ctx, st, func(tx VectorStore) error {
+		if err := tx.SetPayload(ctx, c, map[string]any{"flag": true}, all); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatalf("expected the error from fn")
+	}
+	n, err := st.Count(ctx, c, flagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.(Transactional); ok && n != 0 {
+		t.Fatalf("transaction must roll back, %d points flagged", n)
+	}
+}
+
+func testAliases(t *testing.T, st VectorStore, c string) {
+	seedStore(t, st, c)
+	ctx := context.Background()
+	as := st.(AliasStore)
+	if got, err := as.ResolveAlias(ctx, "live"); err != nil || got != "" {
+		t.Fatalf("expected no alias yet, got %q %v", got, err)
+	}
+	if err := as.SwitchAlias(ctx, "live", "missing"); !errors.Is(err, qdrant.ErrNotFound) {
+		t.Fatalf("alias to a missing collection: expected ErrNotFound, got %v", err)
+	}
+	if err := as.SwitchAlias(ctx, "live", c); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := st.Count(ctx, "live", nil); err != nil || n != 5 {
+		t.Fatalf("expected reads through the alias, got %d %v", n, err)
+	}
+	if err := st.EnsureCollection(ctx, "next", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := as.SwitchAlias(ctx, "live", "next"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Upsert(ctx, "live", []qdrant.Point{{ID: "x", Vector: []float32{1, 0, 0}}}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := st.CollectionInfo(ctx, "live"); info.VectorSize != 3 {
+		t.Fatalf("expected the alias to follow the switch, got %+v", info)
+	}
+	if names, err := as.ListCollections(ctx); err != nil || len(names) != 2 || names[0] != c || names[1] != "next" {
+		t.Fatalf("expected %s and next without the alias, got %v %v", c, names, err)
+	}
+	if err := as.DeleteCollection(ctx, "next"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := as.ResolveAlias(ctx, "live"); got != "" {
+		t.Fatalf("deleting a collection must drop its aliases, got %q", got)
+	}
+	if n, _ := st.Count(ctx, c, nil); n != 5 {
+		t.Fatalf("other collections must be untouched, got %d", n)
+	}
+}